@@ -7,6 +7,7 @@ import (
 
 	"github.com/dagulv/screener/internal/adapter/cron"
 	"github.com/dagulv/screener/internal/adapter/http"
+	"github.com/dagulv/screener/internal/adapter/nasdaq"
 	"github.com/dagulv/screener/internal/adapter/postgres"
 	"github.com/dagulv/screener/internal/core/service"
 	"github.com/dagulv/screener/internal/env"
@@ -37,8 +38,13 @@ func start(ctx context.Context) (err error) {
 	currencyStore := postgres.NewCurrency(db)
 	sectorStore := postgres.NewSector(db)
 	companyStore := postgres.NewCompany(db)
-	// scraper := scraper.NewScraper(ctx, env, currencyStore, companyStore)
+	// scraper := scraper.NewScraper(ctx, env, currencyStore, companyStore, nasdaq.NewPriceFeed())
 	screenerStore := postgres.NewScreener(db)
+	savedScreenStore := postgres.NewSavedScreen(db)
+	rankingStore := postgres.NewRanking(db)
+	backtestStore := postgres.NewBacktest(db)
+	viewStore := postgres.NewView(db)
+	priceStore := postgres.NewPrice(db)
 
 	scheduler, err := cron.New()
 
@@ -46,15 +52,38 @@ func start(ctx context.Context) (err error) {
 		return
 	}
 
-	currencyService := service.NewCurrency(currencyStore, env, scheduler)
+	currencyService := service.NewCurrency(currencyStore, viewStore, env, scheduler)
 
 	if err = currencyService.StartJobs(ctx); err != nil {
 		return
 	}
 
+	savedScreenService := service.NewSavedScreen(savedScreenStore, screenerStore, scheduler)
+
+	if err = savedScreenService.StartJobs(ctx); err != nil {
+		return
+	}
+
+	priceService := service.NewPrice(priceStore, nasdaq.NewPriceFeed(), viewStore, scheduler)
+
+	if err = priceService.StartJobs(ctx); err != nil {
+		return
+	}
+
+	dividendService := service.NewDividend(companyStore, nasdaq.NewDividendFeed(), viewStore, scheduler)
+
+	if err = dividendService.StartJobs(ctx); err != nil {
+		return
+	}
+
 	service := http.Service{
-		Company:  service.NewCompany(companyStore, currencyStore, sectorStore, screenerStore),
-		Screener: service.NewScreener(screenerStore),
+		Company:     service.NewCompany(companyStore, currencyStore, sectorStore, screenerStore, viewStore),
+		Screener:    service.NewScreener(screenerStore),
+		SavedScreen: savedScreenService,
+		Ranking:     service.NewRanking(rankingStore),
+		Backtest:    service.NewBacktest(backtestStore, screenerStore),
+		View:        service.NewView(viewStore),
+		Price:       priceService,
 	}
 
 	api, err := http.NewApi(env, service, nil)
@@ -0,0 +1,51 @@
+package route
+
+import (
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/service"
+	"github.com/webmafia/papi"
+)
+
+type Admin struct {
+	View  service.View
+	Price service.Price
+}
+
+func (r Admin) IterateViewRefreshes(api *papi.API) error {
+	type req struct{}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.ViewRefresh]]{
+		Path: "/admin/views",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.ViewRefresh]) (err error) {
+			return out.WriteAll(r.View.IterateRefreshes(ctx))
+		},
+	})
+}
+
+func (r Admin) RefreshViews(api *papi.API) error {
+	type req struct{}
+
+	return papi.POST(api, papi.Route[req, papi.List[domain.ViewRefresh]]{
+		Path: "/admin/views/refresh",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.ViewRefresh]) (err error) {
+			if err = r.View.Refresh(ctx); err != nil {
+				return
+			}
+
+			return out.WriteAll(r.View.IterateRefreshes(ctx))
+		},
+	})
+}
+
+func (r Admin) IteratePriceIngestions(api *papi.API) error {
+	type req struct {
+		Filter domain.PriceIngestionFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.PriceIngestion]]{
+		Path: "/admin/prices",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.PriceIngestion]) (err error) {
+			return out.WriteAll(r.Price.IterateIngestions(ctx, in.Filter))
+		},
+	})
+}
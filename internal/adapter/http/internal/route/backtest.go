@@ -0,0 +1,40 @@
+package route
+
+import (
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/service"
+	"github.com/webmafia/papi"
+)
+
+type Backtest struct {
+	Service service.Backtest
+}
+
+func (r Backtest) RunBacktest(api *papi.API) error {
+	type req struct {
+		Body domain.Backtest `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, domain.BacktestResult]{
+		Path: "/backtests",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.BacktestResult) (err error) {
+			*out, err = r.Service.Run(ctx, in.Body)
+			return
+		},
+	})
+}
+
+func (r Backtest) DownloadBacktest(api *papi.API) error {
+	type req struct {
+		Body domain.Backtest `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, papi.File[domain.FinancialsFile]]{
+		Path: "/backtests/download",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.File[domain.FinancialsFile]) (err error) {
+			out.SetFilename("backtest.xlsx")
+
+			return r.Service.Download(ctx, in.Body, out.Writer())
+		},
+	})
+}
@@ -135,6 +135,209 @@ func (r Company) IterateFinancials(api *papi.API) error {
 	})
 }
 
+func (r Company) CreateFinancials(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID            `param:"id"`
+		Body      domain.Financials `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, domain.Financials]{
+		Path: "/financials/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.Financials) (err error) {
+			in.Body.CompanyID = in.CompanyId
+			err = r.Service.CreateFinancials(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r Company) IterateShares(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID `param:"id"`
+		Filter    domain.ShareFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.Share]]{
+		Path: "/companies/{id}/prices",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Share]) (err error) {
+			in.Filter.Include = []xid.ID{in.CompanyId}
+
+			return out.WriteAll(r.Service.IterateShares(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Company) IterateFinancialScores(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID `param:"id"`
+		Filter    domain.FinancialScoresFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.FinancialScores]]{
+		Path: "/companies/{id}/scores",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.FinancialScores]) (err error) {
+			in.Filter.Include = []xid.ID{in.CompanyId}
+
+			count, err := r.Service.CountFinancialScores(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateFinancialScores(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Company) CreateDividend(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID          `param:"id"`
+		Body      domain.Dividend `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, domain.Dividend]{
+		Path: "/companies/{id}/dividends",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.Dividend) (err error) {
+			in.Body.CompanyID = in.CompanyId
+			err = r.Service.CreateDividend(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r Company) IterateDividends(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID `param:"id"`
+		Filter    domain.DividendFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.Dividend]]{
+		Path: "/companies/{id}/dividends",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Dividend]) (err error) {
+			in.Filter.Include = []xid.ID{in.CompanyId}
+
+			count, err := r.Service.CountDividends(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateDividends(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Company) IterateTotalReturns(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID `param:"id"`
+		Filter    domain.TotalReturnFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.TotalReturn]]{
+		Path: "/companies/{id}/total-return",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.TotalReturn]) (err error) {
+			in.Filter.Include = []xid.ID{in.CompanyId}
+
+			count, err := r.Service.CountTotalReturns(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateTotalReturns(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Company) SetCorporateAction(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID                 `param:"id"`
+		Body      domain.CorporateAction `body:"json"`
+	}
+
+	return papi.PUT(api, papi.Route[req, domain.CorporateAction]{
+		Path: "/companies/{id}/corporate-actions",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.CorporateAction) (err error) {
+			in.Body.CompanyID = in.CompanyId
+			err = r.Service.SetCorporateAction(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r Company) DeleteCorporateAction(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID                     `param:"id"`
+		Date      time.Time                  `query:"date"`
+		Kind      domain.CorporateActionKind `query:"kind" enum:"split,reverse_split,bonus_issue,spin_off"`
+	}
+
+	return papi.DELETE(api, papi.Route[req, domain.CorporateAction]{
+		Path: "/companies/{id}/corporate-actions",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.CorporateAction) (err error) {
+			return r.Service.DeleteCorporateAction(ctx, &domain.CorporateAction{
+				CompanyID: in.CompanyId,
+				Date:      in.Date,
+				Kind:      in.Kind,
+			})
+		},
+	})
+}
+
+func (r Company) IterateCorporateActions(api *papi.API) error {
+	type req struct {
+		CompanyId xid.ID `param:"id"`
+		Filter    domain.CorporateActionFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.CorporateAction]]{
+		Path: "/companies/{id}/corporate-actions",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.CorporateAction]) (err error) {
+			in.Filter.Include = []xid.ID{in.CompanyId}
+
+			count, err := r.Service.CountCorporateActions(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateCorporateActions(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Company) GetPeerComparison(api *papi.API) error {
+	type req struct {
+		CompanyID xid.ID `param:"id"`
+		Filter    domain.PeerFilter
+	}
+
+	return papi.GET(api, papi.Route[req, domain.PeerComparison]{
+		Path: "/companies/{id}/peers",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.PeerComparison) (err error) {
+			if in.Filter.FiscalYear == 0 {
+				now := time.Now()
+				y, _, _ := now.Date()
+				in.Filter.FiscalYear = y - 1
+			}
+
+			out.CompanyID = in.CompanyID
+			return r.Service.ReadPeerComparison(ctx, out, in.Filter)
+		},
+	})
+}
+
 func (r Company) DownloadFinancials(api *papi.API) (err error) {
 	type req struct {
 		Filter domain.ScreenerFilter
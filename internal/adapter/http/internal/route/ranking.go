@@ -0,0 +1,128 @@
+package route
+
+import (
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/service"
+	"github.com/rs/xid"
+	"github.com/webmafia/papi"
+)
+
+type Ranking struct {
+	Service service.Ranking
+}
+
+func (r Ranking) CreateRanking(api *papi.API) error {
+	type req struct {
+		Body domain.Ranking `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, domain.Ranking]{
+		Path: "/rankings",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.Ranking) (err error) {
+			err = r.Service.Create(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r Ranking) GetRankingDefinition(api *papi.API) error {
+	type req struct {
+		RankingID xid.ID `param:"id"`
+	}
+
+	return papi.GET(api, papi.Route[req, domain.Ranking]{
+		Path: "/rankings/{id}/definition",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.Ranking) (err error) {
+			out.ID = in.RankingID
+			return r.Service.Read(ctx, out)
+		},
+	})
+}
+
+func (r Ranking) UpdateRanking(api *papi.API) error {
+	type req struct {
+		RankingID xid.ID         `param:"id"`
+		Body      domain.Ranking `body:"json"`
+	}
+
+	return papi.PUT(api, papi.Route[req, domain.Ranking]{
+		Path: "/rankings/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.Ranking) (err error) {
+			in.Body.ID = in.RankingID
+			err = r.Service.Update(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r Ranking) DeleteRanking(api *papi.API) error {
+	type req struct {
+		RankingID xid.ID `param:"id"`
+	}
+
+	return papi.DELETE(api, papi.Route[req, domain.Ranking]{
+		Path: "/rankings/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.Ranking) (err error) {
+			return r.Service.Delete(ctx, in.RankingID)
+		},
+	})
+}
+
+func (r Ranking) IterateRankings(api *papi.API) error {
+	type req struct {
+		Filter domain.RankingFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.Ranking]]{
+		Path: "/rankings",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Ranking]) (err error) {
+			count, err := r.Service.Count(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.Iterate(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Ranking) IterateCompositeRanks(api *papi.API) error {
+	type req struct {
+		RankingID xid.ID `param:"id"`
+		Filter    domain.CompositeRankFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.CompositeRank]]{
+		Path: "/rankings/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.CompositeRank]) (err error) {
+			ranking := domain.Ranking{ID: in.RankingID}
+
+			if err = r.Service.Read(ctx, &ranking); err != nil {
+				return
+			}
+
+			if in.Filter.FiscalYear == 0 {
+				now := time.Now()
+				y, _, _ := now.Date()
+				in.Filter.FiscalYear = y - 1
+			}
+
+			count, err := r.Service.CountRanks(ctx, ranking, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateRanks(ctx, ranking, in.Filter))
+		},
+	})
+}
@@ -0,0 +1,208 @@
+package route
+
+import (
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/service"
+	"github.com/rs/xid"
+	"github.com/webmafia/papi"
+)
+
+type SavedScreen struct {
+	Service  service.SavedScreen
+	Screener service.Screener
+	Company  service.Company
+}
+
+func (r SavedScreen) CreateSavedScreen(api *papi.API) error {
+	type req struct {
+		Body domain.SavedScreen `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, domain.SavedScreen]{
+		Path: "/screens",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.SavedScreen) (err error) {
+			err = r.Service.Create(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r SavedScreen) GetSavedScreen(api *papi.API) error {
+	type req struct {
+		ScreenID xid.ID `param:"id"`
+	}
+
+	return papi.GET(api, papi.Route[req, domain.SavedScreen]{
+		Path: "/screens/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.SavedScreen) (err error) {
+			out.ID = in.ScreenID
+			return r.Service.Read(ctx, out)
+		},
+	})
+}
+
+func (r SavedScreen) UpdateSavedScreen(api *papi.API) error {
+	type req struct {
+		ScreenID xid.ID             `param:"id"`
+		Body     domain.SavedScreen `body:"json"`
+	}
+
+	return papi.PUT(api, papi.Route[req, domain.SavedScreen]{
+		Path: "/screens/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.SavedScreen) (err error) {
+			in.Body.ID = in.ScreenID
+			err = r.Service.Update(ctx, &in.Body)
+			*out = in.Body
+			return
+		},
+	})
+}
+
+func (r SavedScreen) DeleteSavedScreen(api *papi.API) error {
+	type req struct {
+		ScreenID xid.ID `param:"id"`
+	}
+
+	return papi.DELETE(api, papi.Route[req, domain.SavedScreen]{
+		Path: "/screens/{id}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *domain.SavedScreen) (err error) {
+			return r.Service.Delete(ctx, in.ScreenID)
+		},
+	})
+}
+
+func (r SavedScreen) IterateSavedScreens(api *papi.API) error {
+	type req struct {
+		Filter domain.SavedScreenFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.SavedScreen]]{
+		Path: "/screens",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.SavedScreen]) (err error) {
+			count, err := r.Service.Count(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.Iterate(ctx, in.Filter))
+		},
+	})
+}
+
+func (r SavedScreen) IterateSavedScreenResults(api *papi.API) error {
+	type req struct {
+		ScreenID xid.ID `param:"id"`
+		Run      domain.SavedScreenRun
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.Screener]]{
+		Path: "/screens/{id}/results",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Screener]) (err error) {
+			filter, err := r.Service.Filter(ctx, in.ScreenID, in.Run)
+
+			if err != nil {
+				return
+			}
+
+			if filter.FiscalYear == 0 {
+				now := time.Now()
+				y, _, _ := now.Date()
+				filter.FiscalYear = y - 1
+			}
+
+			return out.WriteAll(r.Screener.IterateScreenerPage(ctx, filter, out.SetTotal))
+		},
+	})
+}
+
+func (r SavedScreen) DownloadSavedScreen(api *papi.API) error {
+	type req struct {
+		ScreenID   xid.ID          `param:"id"`
+		FiscalYear int             `query:"fiscalYear"`
+		Currency   string          `query:"currency" enum:"SEK,EUR,DKK,ISK,USD"`
+		FXPolicy   domain.FXPolicy `query:"fxPolicy" enum:"standard,average,closing"`
+	}
+
+	return papi.GET(api, papi.Route[req, papi.File[domain.FinancialsFile]]{
+		Path: "/screens/{id}/download",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.File[domain.FinancialsFile]) (err error) {
+			filter, err := r.Service.Filter(ctx, in.ScreenID, domain.SavedScreenRun{
+				Limit:      1000,
+				FiscalYear: in.FiscalYear,
+				Currency:   in.Currency,
+				FXPolicy:   in.FXPolicy,
+			})
+
+			if err != nil {
+				return
+			}
+
+			if filter.FiscalYear == 0 {
+				now := time.Now()
+				y, _, _ := now.Date()
+				filter.FiscalYear = y - 1
+			}
+
+			out.SetFilename("screen.xlsx")
+
+			return r.Company.DownloadFinancials(ctx, filter, out.Writer())
+		},
+	})
+}
+
+func (r SavedScreen) IterateScreenSnapshots(api *papi.API) error {
+	type req struct {
+		ScreenID xid.ID `param:"id"`
+		Filter   domain.ScreenSnapshotFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.ScreenSnapshot]]{
+		Path: "/screens/{id}/snapshots",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.ScreenSnapshot]) (err error) {
+			in.Filter.ScreenID = in.ScreenID
+			count, err := r.Service.CountSnapshots(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateSnapshots(ctx, in.Filter))
+		},
+	})
+}
+
+func (r SavedScreen) IterateScreenChanges(api *papi.API) error {
+	type req struct {
+		ScreenID xid.ID `param:"id"`
+		Filter   domain.ScreenDiffFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.ScreenMemberChange]]{
+		Path: "/screens/{id}/diff",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.ScreenMemberChange]) (err error) {
+			return out.WriteAll(r.Service.IterateChanges(ctx, in.ScreenID, in.Filter))
+		},
+	})
+}
+
+func (r SavedScreen) IterateScreenMembership(api *papi.API) error {
+	type req struct {
+		ScreenID  xid.ID `param:"id"`
+		CompanyID xid.ID `param:"companyId"`
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.ScreenMembership]]{
+		Path: "/screens/{id}/timeline/{companyId}",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.ScreenMembership]) (err error) {
+			return out.WriteAll(r.Service.IterateMembership(ctx, in.ScreenID, in.CompanyID))
+		},
+	})
+}
@@ -32,7 +32,82 @@ func (r Screener) IterateScreener(api *papi.API) error {
 				in.Filter.FiscalYear = y - 1
 			}
 
-			count, err := r.Service.CountScreener(ctx, in.Filter)
+			return out.WriteAll(r.Service.IterateScreenerPage(ctx, in.Filter, out.SetTotal))
+		},
+	})
+}
+
+func (r Screener) QueryScreener(api *papi.API) error {
+	type req struct {
+		Filter domain.ScreenerFilter
+		Body   domain.ScreenerQuery `body:"json"`
+	}
+
+	return papi.POST(api, papi.Route[req, papi.List[domain.Screener]]{
+		Path: "/screener/query",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Screener]) (err error) {
+			if in.Filter.FiscalYear == 0 {
+				now := time.Now()
+				y, _, _ := now.Date()
+				in.Filter.FiscalYear = y - 1
+			}
+
+			in.Filter.Expression = in.Body.Expression
+
+			return out.WriteAll(r.Service.IterateScreenerPage(ctx, in.Filter, out.SetTotal))
+		},
+	})
+}
+
+func (r Screener) IterateMetrics(api *papi.API) error {
+	type req struct{}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.Metric]]{
+		Path: "/metrics",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Metric]) (err error) {
+			out.SetTotal(len(domain.Metrics))
+
+			return out.WriteAll(r.Service.IterateMetrics(ctx))
+		},
+	})
+}
+
+func (r Screener) IterateMagicRanks(api *papi.API) error {
+	type req struct {
+		Filter domain.MagicRankFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.MagicRank]]{
+		Path: "/magic-formula",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.MagicRank]) (err error) {
+			if in.Filter.FiscalYear == 0 {
+				now := time.Now()
+				y, _, _ := now.Date()
+				in.Filter.FiscalYear = y - 1
+			}
+
+			count, err := r.Service.CountMagicRanks(ctx, in.Filter)
+
+			if err != nil {
+				return
+			}
+
+			out.SetTotal(count)
+
+			return out.WriteAll(r.Service.IterateMagicRanks(ctx, in.Filter))
+		},
+	})
+}
+
+func (r Screener) IterateAggregates(api *papi.API) error {
+	type req struct {
+		Filter domain.AggregateFilter
+	}
+
+	return papi.GET(api, papi.Route[req, papi.List[domain.Aggregate]]{
+		Path: "/aggregates",
+		Handler: func(ctx *papi.RequestCtx, in *req, out *papi.List[domain.Aggregate]) (err error) {
+			count, err := r.Service.CountAggregates(ctx, in.Filter)
 
 			if err != nil {
 				return
@@ -40,7 +115,7 @@ func (r Screener) IterateScreener(api *papi.API) error {
 
 			out.SetTotal(count)
 
-			return out.WriteAll(r.Service.IterateScreener(ctx, in.Filter))
+			return out.WriteAll(r.Service.IterateAggregates(ctx, in.Filter))
 		},
 	})
 }
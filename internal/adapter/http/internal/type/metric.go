@@ -0,0 +1,45 @@
+package types
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/webmafia/papi/openapi"
+	"github.com/webmafia/papi/registry"
+)
+
+type metricType struct{}
+
+func Metric() registry.TypeRegistrar {
+	return metricType{}
+}
+
+// Type implements registry.TypeRegistrar.
+func (m metricType) Type() reflect.Type {
+	return reflect.TypeFor[domain.MetricID]()
+}
+
+// TypeDescription implements registry.TypeRegistrar.
+func (m metricType) TypeDescription(reg *registry.Registry) registry.TypeDescription {
+	return registry.TypeDescription{
+		Schema: func(tags reflect.StructTag) (openapi.Schema, error) {
+			return &openapi.String{
+				Description: "Metric ID as listed by `GET /metrics`, or a trend or sector-relative variant of one, e.g. `revenue_cagr_5y` or `evebit_sector_pct`.",
+				Default:     tags.Get("default"),
+			}, nil
+		},
+		Parser: func(tags reflect.StructTag) (registry.Parser, error) {
+			return func(p unsafe.Pointer, s string) (err error) {
+				id := (*domain.MetricID)(p)
+
+				if _, ok := domain.LookupMetric(domain.MetricID(s)); !ok {
+					return domain.ErrUnknownMetric.Detailed("unknown metric \""+s+"\"", "")
+				}
+
+				*id = domain.MetricID(s)
+				return
+			}, nil
+		},
+	}
+}
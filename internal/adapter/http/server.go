@@ -21,8 +21,13 @@ type Server struct {
 }
 
 type Service struct {
-	Company  service.Company
-	Screener service.Screener
+	Company     service.Company
+	Screener    service.Screener
+	SavedScreen service.SavedScreen
+	Ranking     service.Ranking
+	Backtest    service.Backtest
+	View        service.View
+	Price       service.Price
 }
 
 func NewApi(env *env.Environment, service Service, gatekeeper security.Gatekeeper) (s *Server, err error) {
@@ -63,9 +68,17 @@ func NewApi(env *env.Environment, service Service, gatekeeper security.Gatekeepe
 		return
 	}
 
+	if err = api.RegisterType(types.Metric()); err != nil {
+		return
+	}
+
 	err = api.RegisterRoutes(
 		route.Company{Service: service.Company},
 		route.Screener{Service: service.Screener},
+		route.SavedScreen{Service: service.SavedScreen, Screener: service.Screener, Company: service.Company},
+		route.Ranking{Service: service.Ranking},
+		route.Backtest{Service: service.Backtest},
+		route.Admin{View: service.View, Price: service.Price},
 	)
 
 	if err != nil {
@@ -0,0 +1,106 @@
+package nasdaq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+)
+
+type dividendFeed struct {
+	client *http.Client
+}
+
+func NewDividendFeed() port.DividendFeed {
+	return dividendFeed{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type dividendRow struct {
+	ExDivDate      string `json:"exDivDate"`
+	DividendAmount string `json:"dividendAmount"`
+	Currency       string `json:"currency"`
+}
+
+type dividendRoot struct {
+	Data struct {
+		Dividends struct {
+			Rows []dividendRow `json:"rows"`
+		} `json:"dividends"`
+	} `json:"data"`
+}
+
+// GetDividends implements port.DividendFeed
+func (f dividendFeed) GetDividends(ctx context.Context, companyId xid.ID, orderbookId string) iter.Seq2[*domain.Dividend, error] {
+	return func(yield func(*domain.Dividend, error) bool) {
+		req, err := http.NewRequestWithContext(ctx, "GET", baseUrl+orderbookId+"/dividends?assetClass=SHARES&lang=en", nil)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/135.0.0.0 Safari/537.36")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Referer", "https://nasdaq.com")
+
+		resp, err := f.client.Do(req)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			yield(nil, errors.New("bad status: "+resp.Status))
+			return
+		}
+
+		var data dividendRoot
+
+		if err = json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, raw := range data.Data.Dividends.Rows {
+			dividend, err := parseDividend(companyId, raw)
+
+			if !yield(dividend, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Amounts are per share in the currency they were declared in, e.g. "4.50".
+func parseDividend(companyId xid.ID, raw dividendRow) (dividend *domain.Dividend, err error) {
+	dividend = &domain.Dividend{
+		CompanyID: companyId,
+		Currency:  domain.IDAndName{Name: strings.ToUpper(strings.TrimSpace(raw.Currency))},
+	}
+
+	if dividend.ExDate, err = time.Parse(time.DateOnly, raw.ExDivDate); err != nil {
+		return nil, err
+	}
+
+	amount, err := toFloat(raw.DividendAmount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dividend.Amount = amount / 100
+
+	return
+}
@@ -0,0 +1,121 @@
+package nasdaq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+)
+
+const baseUrl = "https://api.nasdaq.com/api/nordic/instruments/"
+
+type priceFeed struct {
+	client *http.Client
+}
+
+func NewPriceFeed() port.PriceFeed {
+	return priceFeed{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetShares implements port.PriceFeed
+func (f priceFeed) GetShares(ctx context.Context, companyId xid.ID, orderbookId string, from time.Time, to time.Time) iter.Seq2[*domain.Share, error] {
+	return func(yield func(*domain.Share, error) bool) {
+		req, err := http.NewRequestWithContext(ctx, "GET", baseUrl+orderbookId+"/chart/download?assetClass=SHARES&fromDate="+from.Format(time.DateOnly)+"&toDate="+to.Format(time.DateOnly), nil)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/135.0.0.0 Safari/537.36")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Referer", "https://nasdaq.com")
+
+		resp, err := f.client.Do(req)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			yield(nil, errors.New("bad status: "+resp.Status))
+			return
+		}
+
+		var data domain.RawRoot
+
+		if err = json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, raw := range data.Data.Charts.Rows {
+			share, err := parseShare(companyId, raw)
+
+			if !yield(share, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Prices and volumes are in the same precision as they're stored.
+func parseShare(companyId xid.ID, raw domain.Row) (share *domain.Share, err error) {
+	share = &domain.Share{CompanyID: companyId}
+
+	if share.Date, err = time.Parse(time.DateOnly, raw.DateTime); err != nil {
+		return nil, err
+	}
+
+	for _, v := range [...]struct {
+		dst *float64
+		src string
+	}{
+		{&share.Open, raw.Open},
+		{&share.High, raw.High},
+		{&share.Low, raw.Low},
+		{&share.Close, raw.Close},
+		{&share.Average, raw.Average},
+	} {
+		if *v.dst, err = toFloat(v.src); err != nil {
+			return nil, err
+		}
+	}
+
+	volume, err := toFloat(raw.TotalVolume)
+
+	if err != nil {
+		return nil, err
+	}
+
+	share.Volume = int(volume)
+
+	return
+}
+
+func toFloat(input string) (float64, error) {
+	if input == "" {
+		return 0, nil
+	}
+
+	formatted, err := strconv.ParseFloat(strings.ReplaceAll(input, ",", ""), 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return formatted * 100, nil
+}
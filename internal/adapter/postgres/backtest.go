@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+	"github.com/webmafia/pg"
+)
+
+type backtestStore struct {
+	db
+}
+
+func NewBacktest(pool *pg.DB) port.Backtest {
+	return backtestStore{
+		db: db{pool},
+	}
+}
+
+// IterateClosingPrices implements port.Backtest
+func (s backtestStore) IterateClosingPrices(ctx context.Context, companyIds []xid.ID, dates []time.Time) iter.Seq2[*domain.ClosingPrice, error] {
+	return func(yield func(*domain.ClosingPrice, error) bool) {
+		rows, err := s.db.Query(ctx, `
+			select
+				c.id,
+				d.date,
+				p.date,
+				p.close,
+				p.total_return
+			from unnest(%c::timestamp[]) d(date)
+			cross join unnest(%c::text[]) c(id)
+			inner join lateral (
+				select
+					t.date,
+					t.close::float,
+					t.total_return
+				from %T t
+				where t.company_id = c.id and t.date <= d.date
+				order by t.date desc
+				limit 1
+			) p on true
+		`, dates, companyIds, TotalReturns)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var price domain.ClosingPrice
+
+			if err = rows.Scan(
+				&price.CompanyID,
+				&price.Date,
+				&price.PriceDate,
+				&price.Close,
+				&price.TotalReturn,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			price.Close /= 100
+			price.TotalReturn /= 100
+
+			if !yield(&price, nil) {
+				return
+			}
+		}
+	}
+}
+
+// LastShareDate implements port.Backtest
+func (s backtestStore) LastShareDate(ctx context.Context) (date time.Time, err error) {
+	row := s.db.QueryRow(ctx, `
+		select
+			coalesce(max(s.date), now())
+		from %T s
+	`, Share)
+
+	err = row.Scan(&date)
+
+	return
+}
@@ -3,6 +3,9 @@ package postgres
 import (
 	"context"
 	"iter"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/dagulv/screener/internal/core/domain"
 	"github.com/dagulv/screener/internal/core/port"
@@ -207,7 +210,13 @@ func (s companyStore) CreateFinancials(ctx context.Context, financials *domain.F
 
 	vals.
 		Value("company_id", financials.CompanyID).
-		Value("fiscal_year", financials.FiscalYear).
+		Value("fiscal_year", financials.FiscalYear)
+
+	if financials.Period.Interim() {
+		vals.Value("period", financials.Period)
+	}
+
+	vals.
 		Value("currency", financials.CurrencyID).
 		Value("revenue", financials.StaticData.Revenue).
 		Value("cost_of_revenue", financials.StaticData.CostOfRevenue).
@@ -225,17 +234,29 @@ func (s companyStore) CreateFinancials(ctx context.Context, financials *domain.F
 		Value("capital_expenditures", financials.StaticData.CapitalExpenditures).
 		Value("free_cash_flow", financials.StaticData.FreeCashFlow).
 		Value("number_of_shares", financials.StaticData.NumberOfShares).
-		Value("ppe", financials.StaticData.PPE)
+		Value("ppe", financials.StaticData.PPE).
+		Value("current_assets", financials.StaticData.CurrentAssets).
+		Value("current_liabilities", financials.StaticData.CurrentLiabilities).
+		Value("retained_earnings", financials.StaticData.RetainedEarnings).
+		Value("receivables", financials.StaticData.Receivables).
+		Value("depreciation", financials.StaticData.Depreciation).
+		Value("sga", financials.StaticData.SGA)
+
+	// Quarters and half-years are upserted by company, fiscal year and period, and fiscal years by
+	// company and fiscal year
+	if financials.Period.Interim() {
+		_, err = s.db.InsertValues(ctx, InterimFinancials, vals, pg.InsertOptions{OnConflict: pg.DoUpdate(3, "company_id", "fiscal_year", "period")})
+		return
+	}
 
-	// _, err = s.db.InsertValues(ctx, Financials, vals, pg.InsertOptions{OnConflict: pg.DoUpdate(2, "company_id", "fiscal_year")})
-	_, err = s.db.UpdateValues(ctx, Financials, vals, pg.And(pg.Eq("company_id", financials.CompanyID), pg.Eq("fiscal_year", financials.FiscalYear)))
+	_, err = s.db.InsertValues(ctx, Financials, vals, pg.InsertOptions{OnConflict: pg.DoUpdate(2, "company_id", "fiscal_year")})
 
 	return
 }
 
 // CountFinancials implements port.Company
 func (s companyStore) CountFinancials(ctx context.Context, filters domain.FinancialFilter) (count int, err error) {
-	f := Financials.Alias("f")
+	f, _, _ := financialsSource(filters.Period)
 	cond := financialsFilter(filters, f)
 
 	row := s.db.QueryRow(ctx, `
@@ -253,14 +274,23 @@ func (s companyStore) CountFinancials(ctx context.Context, filters domain.Financ
 // IterateFinancials implements port.Company
 func (s companyStore) IterateFinancials(ctx context.Context, filters domain.FinancialFilter) iter.Seq2[*domain.Financials, error] {
 	return func(yield func(*domain.Financials, error) bool) {
-		f := Financials.Alias("f")
+		f, period, derived := financialsSource(filters.Period)
 		df := DerivedFinancials.Alias("df")
+		curr := Currency.Alias("curr")
 		cond := financialsFilter(filters, f)
+		rates := pg.QueryEncoder(pg.Raw("null::float8, null::float8"))
+		conversion := pg.QueryEncoder(pg.Raw(""))
+
+		if filters.Currency != "" {
+			rates = pg.Raw("fx.flow, fx.balance")
+			conversion = currencyConversion(filters.FXPolicy, filters.Currency)
+		}
 
 		rows, err := s.db.Query(ctx, `
 			select
 				f.company_id,
 				f.fiscal_year,
+				%T,
 				f.currency,
 				f.revenue,
 				f.cost_of_revenue,
@@ -279,6 +309,12 @@ func (s companyStore) IterateFinancials(ctx context.Context, filters domain.Fina
 				f.free_cash_flow,
 				f.number_of_shares,
 				f.ppe,
+				f.current_assets::bigint,
+				f.current_liabilities::bigint,
+				f.retained_earnings::bigint,
+				f.receivables::bigint,
+				f.depreciation::bigint,
+				f.sga::bigint,
 				df.eps,
 				df.evebit,
 				df.pb,
@@ -291,11 +327,17 @@ func (s companyStore) IterateFinancials(ctx context.Context, filters domain.Fina
 				df.liabilities_to_equity,
 				df.debt_to_ebit,
 				df.debt_to_assets,
-				df.cash_conversion
+				df.cash_conversion,
+				df.market_cap,
+				df.enterprise_value,
+				%T
 			from %T
-			left join %T on df.company_id = f.company_id and df.fiscal_year = f.fiscal_year
+			left join %T on %T
+			left join %T on curr.id = f.currency
+			%T
+			%T
 			where %c
-		`, f, df, cond)
+		`, period, rates, f, df, derived, curr, currencyRates(filters.FXPolicy, pg.Raw("%T", f.Col("fiscal_year")), f.Col("currency")), conversion, cond)
 
 		if err != nil {
 			yield(nil, err)
@@ -310,6 +352,8 @@ func (s companyStore) IterateFinancials(ctx context.Context, filters domain.Fina
 			if err = rows.Scan(
 				&financials.CompanyID,
 				&financials.FiscalYear,
+				&financials.Period,
+				&financials.Through,
 				&financials.CurrencyID,
 				&financials.StaticData.Revenue,
 				&financials.StaticData.CostOfRevenue,
@@ -328,6 +372,12 @@ func (s companyStore) IterateFinancials(ctx context.Context, filters domain.Fina
 				&financials.StaticData.FreeCashFlow,
 				&financials.StaticData.NumberOfShares,
 				&financials.StaticData.PPE,
+				&financials.StaticData.CurrentAssets,
+				&financials.StaticData.CurrentLiabilities,
+				&financials.StaticData.RetainedEarnings,
+				&financials.StaticData.Receivables,
+				&financials.StaticData.Depreciation,
+				&financials.StaticData.SGA,
 				&financials.DerivedData.EPS,
 				&financials.DerivedData.EVEBIT,
 				&financials.DerivedData.PB,
@@ -341,12 +391,24 @@ func (s companyStore) IterateFinancials(ctx context.Context, filters domain.Fina
 				&financials.DerivedData.DebtToEbit,
 				&financials.DerivedData.DebtToAssets,
 				&financials.DerivedData.CashConversion,
+				&financials.DerivedData.MarketCap,
+				&financials.DerivedData.EnterpriseValue,
+				&financials.ExchangeRate,
+				&financials.BalanceExchangeRate,
 			); err != nil {
 				yield(nil, err)
 				return
 			}
 
-			financialsTransform(&financials.StaticData)
+			if financials.ExchangeRate.Valid && financials.BalanceExchangeRate.Valid {
+				financials.DisplayCurrency = filters.Currency
+				financialsConvert(&financials, financials.ExchangeRate.Content, financials.BalanceExchangeRate.Content)
+			} else {
+				financials.ExchangeRate.Valid = false
+				financials.BalanceExchangeRate.Valid = false
+			}
+
+			financialsTransform(&financials)
 
 			if !yield(&financials, nil) {
 				return
@@ -355,7 +417,8 @@ func (s companyStore) IterateFinancials(ctx context.Context, filters domain.Fina
 	}
 }
 
-func financialsTransform(financials *domain.FinancialData) {
+func financialsTransform(f *domain.Financials) {
+	financials := &f.StaticData
 	financials.Revenue *= (1_000_000 / 100)
 	financials.CostOfRevenue *= (1_000_000 / 100)
 	financials.GrossOperatingProfit *= (1_000_000 / 100)
@@ -372,6 +435,78 @@ func financialsTransform(financials *domain.FinancialData) {
 	financials.CapitalExpenditures *= (1_000_000 / 100)
 	financials.FreeCashFlow *= (1_000_000 / 100)
 	financials.PPE *= (1_000_000 / 100)
+	financials.CurrentAssets.Content *= TransformConstant
+	financials.CurrentLiabilities.Content *= TransformConstant
+	financials.RetainedEarnings.Content *= TransformConstant
+	financials.Receivables.Content *= TransformConstant
+	financials.Depreciation.Content *= TransformConstant
+	financials.SGA.Content *= TransformConstant
+	f.DerivedData.MarketCap.Content *= TransformConstant
+	f.DerivedData.EnterpriseValue.Content *= TransformConstant
+}
+
+// Converts monetary values to another currency, with separate exchange rates for income and
+// cash-flow items and for balance-sheet items.
+func financialsConvert(f *domain.Financials, flowRate float64, balanceRate float64) {
+	financials := &f.StaticData
+
+	for _, v := range [...]*int{
+		&financials.Revenue,
+		&financials.CostOfRevenue,
+		&financials.GrossOperatingProfit,
+		&financials.Ebit,
+		&financials.NetIncome,
+		&financials.OperatingCashFlow,
+		&financials.CapitalExpenditures,
+		&financials.FreeCashFlow,
+	} {
+		*v = int(math.Round(float64(*v) * flowRate))
+	}
+
+	for _, v := range [...]*int{
+		&financials.TotalAssets,
+		&financials.TotalLiabilities,
+		&financials.CashAndEquivalents,
+		&financials.ShortTermInvestments,
+		&financials.LongTermDebt,
+		&financials.CurrentDebt,
+		&financials.Equity,
+		&financials.PPE,
+	} {
+		*v = int(math.Round(float64(*v) * balanceRate))
+	}
+
+	for _, v := range [...]*domain.Nullable[int64]{
+		&financials.Depreciation,
+		&financials.SGA,
+	} {
+		v.Content = int64(math.Round(float64(v.Content) * flowRate))
+	}
+
+	for _, v := range [...]*domain.Nullable[int64]{
+		&financials.CurrentAssets,
+		&financials.CurrentLiabilities,
+		&financials.RetainedEarnings,
+		&financials.Receivables,
+		&f.DerivedData.MarketCap,
+		&f.DerivedData.EnterpriseValue,
+	} {
+		v.Content = int64(math.Round(float64(v.Content) * balanceRate))
+	}
+}
+
+// The financials of a kind of period as "f", along with the period columns of each row and the
+// join condition of the derived financials, which only exist for whole fiscal years.
+func financialsSource(kind domain.PeriodKind) (f pg.Alias, period pg.QueryEncoder, derived pg.QueryEncoder) {
+	switch kind {
+	case domain.PeriodKindInterim:
+		return InterimFinancials.Alias("f"), pg.Raw("f.period, ''"), pg.Raw("false")
+
+	case domain.PeriodKindTTM:
+		return TTMFinancials.Alias("f"), pg.Raw("'" + string(domain.FinancialPeriodTTM) + "', f.period"), pg.Raw("false")
+	}
+
+	return Financials.Alias("f"), pg.Raw("'" + string(domain.FinancialPeriodYear) + "', ''"), pg.Raw("df.company_id = f.company_id and df.fiscal_year = f.fiscal_year")
 }
 
 func financialsFilter(filters domain.FinancialFilter, a pg.Alias) pg.QueryEncoder {
@@ -405,24 +540,37 @@ func (s companyStore) CreateShare(ctx context.Context, share *domain.Share) (err
 }
 
 // IterateShares implements port.Company
-func (s companyStore) IterateShares(ctx context.Context, filters domain.CompanyFilter) iter.Seq2[*domain.Share, error] {
+func (s companyStore) IterateShares(ctx context.Context, filters domain.ShareFilter) iter.Seq2[*domain.Share, error] {
 	return func(yield func(*domain.Share, error) bool) {
 		sh := Share.Alias("s")
-		cond := pg.And()
 
+		if filters.Adjusted {
+			sh = AdjustedShares.Alias("s")
+		}
+
+		cond := sharesFilter(filters, sh)
+		interval := filters.Interval
+
+		if interval == "" {
+			interval = domain.ShareIntervalDay
+		}
+
+		// Bars are aggregated by the start of their interval, with the volume-weighted average price
 		rows, err := s.db.Query(ctx, `
 			select
 				s.company_id,
-				s.date,
-				s.open::float,
-				s.high::float,
-				s.low::float,
-				s.close::float,
-				s.volume,
-				s.average::float
+				date_trunc(%c, s.date) as bar,
+				(array_agg(s.open order by s.date))[1]::float,
+				max(s.high)::float,
+				min(s.low)::float,
+				(array_agg(s.close order by s.date desc))[1]::float,
+				sum(s.volume)::bigint,
+				coalesce(sum(s.average::float * s.volume) / nullif(sum(s.volume), 0), avg(s.average))::float
 			from %T
 			where %c
-		`, sh, cond)
+			group by s.company_id, bar
+			order by s.company_id, bar
+		`, string(interval), sh, cond)
 
 		if err != nil {
 			yield(nil, err)
@@ -457,6 +605,24 @@ func (s companyStore) IterateShares(ctx context.Context, filters domain.CompanyF
 	}
 }
 
+func sharesFilter(filters domain.ShareFilter, a pg.Alias) pg.QueryEncoder {
+	cond := pg.And()
+
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(a.Col("company_id"), filters.Include))
+	}
+
+	if !filters.From.IsZero() {
+		cond.And(pg.Gte(a.Col("date"), filters.From))
+	}
+
+	if !filters.To.IsZero() {
+		cond.And(pg.Lte(a.Col("date"), filters.To))
+	}
+
+	return cond
+}
+
 func shareTransform(share *domain.Share) {
 	share.Open /= 100
 	share.High /= 100
@@ -465,6 +631,586 @@ func shareTransform(share *domain.Share) {
 	share.Average /= 100
 }
 
+// CountFinancialScores implements port.Company
+func (s companyStore) CountFinancialScores(ctx context.Context, filters domain.FinancialScoresFilter) (count int, err error) {
+	fs := FinancialScores.Alias("fs")
+	cond := financialScoresFilter(filters, fs)
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, fs, cond)
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateFinancialScores implements port.Company
+func (s companyStore) IterateFinancialScores(ctx context.Context, filters domain.FinancialScoresFilter) iter.Seq2[*domain.FinancialScores, error] {
+	return func(yield func(*domain.FinancialScores, error) bool) {
+		fs := FinancialScores.Alias("fs")
+		cond := financialScoresFilter(filters, fs)
+		cols := []pg.StringEncoder{pg.Col("fs.company_id"), pg.Col("fs.fiscal_year"), pg.Col("fs.f_score::float8"), pg.Col("fs.z_score"), pg.Col("fs.m_score")}
+
+		for _, components := range [...][]domain.ScoreComponent{domain.PiotroskiComponents, domain.AltmanComponents, domain.BeneishComponents} {
+			for _, c := range components {
+				cols = append(cols, pg.Col("fs."+c.ID+"::float8"))
+			}
+		}
+
+		rows, err := s.db.Query(ctx, `
+			select
+				%T
+			from %T
+			where %c
+			order by fs.fiscal_year desc
+			offset %T
+			limit %T
+		`, pg.Columns(cols), fs, cond, filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			scores := domain.FinancialScores{
+				Piotroski: domain.NewScore(domain.PiotroskiComponents),
+				Altman:    domain.NewScore(domain.AltmanComponents),
+				Beneish:   domain.NewScore(domain.BeneishComponents),
+			}
+
+			scans := []any{&scores.CompanyID, &scores.FiscalYear, &scores.Piotroski.Value, &scores.Altman.Value, &scores.Beneish.Value}
+
+			for _, score := range [...]*domain.Score{&scores.Piotroski, &scores.Altman, &scores.Beneish} {
+				for i := range score.Components {
+					scans = append(scans, &score.Components[i].Value)
+				}
+			}
+
+			if err = rows.Scan(scans...); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, score := range [...]*domain.Score{&scores.Piotroski, &scores.Altman, &scores.Beneish} {
+				score.Complete = score.Value.Valid
+			}
+
+			if !yield(&scores, nil) {
+				return
+			}
+		}
+	}
+}
+
+func financialScoresFilter(filters domain.FinancialScoresFilter, a pg.Alias) pg.QueryEncoder {
+	cond := pg.And()
+
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(a.Col("company_id"), filters.Include))
+	}
+
+	return cond
+}
+
+// CreateDividend implements port.Company
+func (s companyStore) CreateDividend(ctx context.Context, dividend *domain.Dividend) (err error) {
+	var currencyId any
+
+	if !dividend.Currency.ID.IsNil() {
+		currencyId = dividend.Currency.ID
+	}
+
+	rows, err := s.db.Query(ctx, `
+		with
+			curr as (
+				select curr.id, curr.name
+				from %T curr
+				where curr.id = %c or (%c::text is null and curr.name = %c)
+			),
+			ins as (
+				insert into %T (company_id, ex_date, amount, currency)
+				select %c, %c, %c, curr.id
+				from curr
+				on conflict (company_id, ex_date) do update set
+					amount = excluded.amount,
+					currency = excluded.currency
+				returning currency
+			)
+		select curr.id, curr.name
+		from curr
+		inner join ins on ins.currency = curr.id
+	`, Currency, currencyId, currencyId, dividend.Currency.Name, Dividends, dividend.CompanyID, dividend.ExDate, int(math.Round(dividend.Amount*100)))
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return
+		}
+
+		return domain.ErrUnknownCurrency.Detailed("unknown currency \""+dividend.Currency.Name+"\"", "currency")
+	}
+
+	return rows.Scan(&dividend.Currency.ID, &dividend.Currency.Name)
+}
+
+// CountDividends implements port.Company
+func (s companyStore) CountDividends(ctx context.Context, filters domain.DividendFilter) (count int, err error) {
+	d := Dividends.Alias("d")
+	cond := dividendsFilter(filters, d)
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, d, cond)
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateDividends implements port.Company
+func (s companyStore) IterateDividends(ctx context.Context, filters domain.DividendFilter) iter.Seq2[*domain.Dividend, error] {
+	return func(yield func(*domain.Dividend, error) bool) {
+		d := Dividends.Alias("d")
+		cond := dividendsFilter(filters, d)
+
+		rows, err := s.db.Query(ctx, `
+			select
+				d.company_id,
+				d.ex_date,
+				d.amount::float,
+				(curr.id,
+				curr.name)
+			from %T
+			inner join %T curr on curr.id = d.currency
+			where %c
+			order by d.ex_date desc
+			offset %T
+			limit %T
+		`, d, Currency, cond, filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var dividend domain.Dividend
+
+			if err = rows.Scan(
+				&dividend.CompanyID,
+				&dividend.ExDate,
+				&dividend.Amount,
+				&dividend.Currency,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			dividend.Amount /= 100
+
+			if !yield(&dividend, nil) {
+				return
+			}
+		}
+	}
+}
+
+func dividendsFilter(filters domain.DividendFilter, a pg.Alias) pg.QueryEncoder {
+	cond := pg.And()
+
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(a.Col("company_id"), filters.Include))
+	}
+
+	return cond
+}
+
+// CountTotalReturns implements port.Company
+func (s companyStore) CountTotalReturns(ctx context.Context, filters domain.TotalReturnFilter) (count int, err error) {
+	t := TotalReturns.Alias("t")
+	cond := totalReturnsFilter(filters, t)
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, t, cond)
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateTotalReturns implements port.Company
+func (s companyStore) IterateTotalReturns(ctx context.Context, filters domain.TotalReturnFilter) iter.Seq2[*domain.TotalReturn, error] {
+	return func(yield func(*domain.TotalReturn, error) bool) {
+		t := TotalReturns.Alias("t")
+		cond := totalReturnsFilter(filters, t)
+
+		rows, err := s.db.Query(ctx, `
+			select
+				t.date,
+				t.close::float,
+				t.dividend,
+				t.total_return
+			from %T
+			where %c
+			order by t.date
+			offset %T
+			limit %T
+		`, t, cond, filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var point domain.TotalReturn
+
+			if err = rows.Scan(
+				&point.Date,
+				&point.Close,
+				&point.Dividend,
+				&point.TotalReturn,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			point.Close /= 100
+			point.Dividend /= 100
+			point.TotalReturn /= 100
+
+			if !yield(&point, nil) {
+				return
+			}
+		}
+	}
+}
+
+func totalReturnsFilter(filters domain.TotalReturnFilter, a pg.Alias) pg.QueryEncoder {
+	cond := pg.And()
+
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(a.Col("company_id"), filters.Include))
+	}
+
+	return cond
+}
+
+// SetCorporateAction implements port.Company
+func (s companyStore) SetCorporateAction(ctx context.Context, action *domain.CorporateAction) (err error) {
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	vals.
+		Value("company_id", action.CompanyID).
+		Value("date", action.Date).
+		Value("kind", action.Kind).
+		Value("ratio", action.Ratio).
+		Value("note", action.Note)
+
+	_, err = s.db.InsertValues(ctx, CorporateActions, vals, pg.InsertOptions{OnConflict: pg.DoUpdate(3, "company_id", "date", "kind")})
+
+	return
+}
+
+// DeleteCorporateAction implements port.Company
+func (s companyStore) DeleteCorporateAction(ctx context.Context, action *domain.CorporateAction) (err error) {
+	_, err = s.db.Delete(ctx, CorporateActions, pg.And(
+		pg.Eq("company_id", action.CompanyID),
+		pg.Eq("date", action.Date),
+		pg.Eq("kind", action.Kind),
+	))
+
+	return
+}
+
+// CountCorporateActions implements port.Company
+func (s companyStore) CountCorporateActions(ctx context.Context, filters domain.CorporateActionFilter) (count int, err error) {
+	ca := CorporateActions.Alias("ca")
+	cond := corporateActionsFilter(filters, ca)
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, ca, cond)
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateCorporateActions implements port.Company
+func (s companyStore) IterateCorporateActions(ctx context.Context, filters domain.CorporateActionFilter) iter.Seq2[*domain.CorporateAction, error] {
+	return func(yield func(*domain.CorporateAction, error) bool) {
+		ca := CorporateActions.Alias("ca")
+		cond := corporateActionsFilter(filters, ca)
+
+		rows, err := s.db.Query(ctx, `
+			select
+				ca.company_id,
+				ca.date,
+				ca.kind,
+				ca.ratio::float8,
+				ca.note
+			from %T
+			where %c
+			order by ca.date desc, ca.kind
+			offset %T
+			limit %T
+		`, ca, cond, filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var action domain.CorporateAction
+
+			if err = rows.Scan(
+				&action.CompanyID,
+				&action.Date,
+				&action.Kind,
+				&action.Ratio,
+				&action.Note,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&action, nil) {
+				return
+			}
+		}
+	}
+}
+
+func corporateActionsFilter(filters domain.CorporateActionFilter, a pg.Alias) pg.QueryEncoder {
+	cond := pg.And()
+
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(a.Col("company_id"), filters.Include))
+	}
+
+	return cond
+}
+
+// ReadPeerComparison implements port.Company
+func (s companyStore) ReadPeerComparison(ctx context.Context, comparison *domain.PeerComparison, filters domain.PeerFilter) (err error) {
+	c := Company.Alias("c")
+
+	row := s.db.QueryRow(ctx, `
+		select
+			c."sectorId"
+		from %T
+		where %c
+	`, c, pg.Eq(c.Col("id"), comparison.CompanyID))
+
+	if err = row.Scan(&comparison.SectorID); err != nil {
+		return
+	}
+
+	comparison.FiscalYear = filters.FiscalYear
+
+	if comparison.Metrics, err = s.peerMetrics(ctx, comparison.CompanyID, filters); err != nil {
+		return
+	}
+
+	comparison.Peers, err = s.peers(ctx, comparison.CompanyID, filters)
+
+	return
+}
+
+// Every derived metric of a company, with its distribution within the sector. Companies without a
+// value are left out of the distribution, and a company without a value has no percentile rank.
+func (s companyStore) peerMetrics(ctx context.Context, companyId xid.ID, filters domain.PeerFilter) (metrics []domain.PeerMetric, err error) {
+	values := make([]string, 0, 16)
+	index := make(map[domain.MetricID]int, 16)
+
+	for _, metric := range domain.Metrics {
+		if metric.Source != domain.MetricSourceDerivedFinancials {
+			continue
+		}
+
+		index[metric.ID] = len(metrics)
+		values = append(values, "('"+string(metric.ID)+"', "+screenerValueExpr(metric)+"::float8)")
+		metrics = append(metrics, domain.PeerMetric{Metric: metric.ID, Label: metric.Label, Unit: metric.Unit})
+	}
+
+	rows, err := s.db.Query(ctx, `
+		with
+			peers as (
+				select
+					c.id = t.id as self,
+					v.metric,
+					v.value
+				from %T t
+				inner join %T c on %T
+				inner join %T df on df.company_id = c.id and df.fiscal_year = %c
+				%T
+				cross join lateral (values `+strings.Join(values, ", ")+`) as v(metric, value)
+				where t.id = %c and v.value is not null
+			)
+		select
+			p.metric,
+			t.value,
+			count(*),
+			percentile_cont(0.25) within group (order by p.value),
+			percentile_cont(0.5) within group (order by p.value),
+			percentile_cont(0.75) within group (order by p.value),
+			case when t.value is not null then (count(*) filter (where p.value < t.value))::float8 / nullif(count(*) - 1, 0) end
+		from peers p
+		left join peers t on t.metric = p.metric and t.self
+		group by p.metric, t.value
+	`,
+		Company, Company, peerScope(filters.SectorScope),
+		DerivedFinancials, filters.FiscalYear,
+		currencyRates(domain.FXPolicyStandard, pg.Raw("%c", filters.FiscalYear), Company.Alias("c").Col("currencyId")),
+		companyId,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id     domain.MetricID
+			metric domain.PeerMetric
+		)
+
+		if err = rows.Scan(
+			&id,
+			&metric.Value,
+			&metric.Count,
+			&metric.P25,
+			&metric.Median,
+			&metric.P75,
+			&metric.Percentile,
+		); err != nil {
+			return
+		}
+
+		if i, ok := index[id]; ok {
+			metric.Metric, metric.Label, metric.Unit = metrics[i].Metric, metrics[i].Label, metrics[i].Unit
+			metrics[i] = metric
+		}
+	}
+
+	return metrics, rows.Err()
+}
+
+// The companies in the same sector that are closest to a company in market cap.
+func (s companyStore) peers(ctx context.Context, companyId xid.ID, filters domain.PeerFilter) (peers []domain.Peer, err error) {
+	peers = make([]domain.Peer, 0, filters.Peers)
+
+	if filters.Peers <= 0 {
+		return
+	}
+
+	marketCap := "df.market_cap::float8 / " + strconv.Itoa(FloatConstant) + " / cr.balance"
+	rates := currencyRates(domain.FXPolicyStandard, pg.Raw("%c", filters.FiscalYear), Company.Alias("c").Col("currencyId"))
+
+	rows, err := s.db.Query(ctx, `
+		with
+			target as (
+				select
+					c.id,
+					c."sectorId",
+					c.country_code,
+					c.market_place_code,
+					`+marketCap+` as market_cap
+				from %T c
+				inner join %T df on df.company_id = c.id and df.fiscal_year = %c
+				%T
+				where c.id = %c
+			)
+		select
+			c.id,
+			c.name,
+			c.country_code,
+			`+marketCap+`,
+			`+marketCap+` / t.market_cap
+		from target t
+		inner join %T c on %T and c.id <> t.id
+		inner join %T df on df.company_id = c.id and df.fiscal_year = %c
+		%T
+		where t.market_cap > 0 and `+marketCap+` > 0
+		order by abs(ln(`+marketCap+` / t.market_cap)), c.name
+		limit %c
+	`,
+		Company, DerivedFinancials, filters.FiscalYear, rates, companyId,
+		Company, peerScope(filters.SectorScope), DerivedFinancials, filters.FiscalYear, rates,
+		filters.Peers,
+	)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var peer domain.Peer
+
+		if err = rows.Scan(
+			&peer.CompanyID,
+			&peer.Name,
+			&peer.CountryCode,
+			&peer.MarketCap,
+			&peer.SizeRatio,
+		); err != nil {
+			return
+		}
+
+		peers = append(peers, peer)
+	}
+
+	return peers, rows.Err()
+}
+
+// Joins the companies "c" that a company "t" is compared with: those in the same sector, and in the
+// same country or marketplace if scoped.
+func peerScope(scope domain.SectorScope) pg.QueryEncoder {
+	switch scope {
+	case domain.SectorScopeCountry:
+		return pg.Raw(`c."sectorId" = t."sectorId" and c.country_code = t.country_code`)
+	case domain.SectorScopeMarketPlace:
+		return pg.Raw(`c."sectorId" = t."sectorId" and c.market_place_code = t.market_place_code`)
+	}
+
+	return pg.Raw(`c."sectorId" = t."sectorId"`)
+}
+
 // IterateFinancials implements port.Company
 func (s companyStore) IterateFinancialsByMissingShare(ctx context.Context) iter.Seq2[*domain.Financials, error] {
 	return func(yield func(*domain.Financials, error) bool) {
@@ -531,6 +1277,8 @@ func (s companyStore) IterateFinancialsByMissingShare(ctx context.Context) iter.
 				return
 			}
 
+			financials.Period = domain.FinancialPeriodYear
+
 			if !yield(&financials, nil) {
 				return
 			}
@@ -123,7 +123,9 @@ func (s currencyStore) IterateCurrencyRates(ctx context.Context, filters domain.
 				r.quarter,
 				(c.id,
 				c.name),
-				r.rate
+				r.rate,
+				coalesce(r.closing, 0),
+				coalesce(r.days, 0)
 			FROM %T
 			left join %T c on c.id = r.currency_id
 		`, r, Currency)
@@ -143,6 +145,8 @@ func (s currencyStore) IterateCurrencyRates(ctx context.Context, filters domain.
 				&rate.Quarter,
 				&rate.Currency,
 				&rate.Rate,
+				&rate.Closing,
+				&rate.Days,
 			); err != nil {
 				yield(nil, err)
 				return
@@ -170,12 +174,105 @@ func (s currencyStore) SetCurrencyRates(ctx context.Context, currencyRates []dom
 			Value("fiscal_year", cr.FiscalYear).
 			Value("quarter", cr.Quarter).
 			Value("currency_id", cr.Currency.ID).
-			Value("rate", cr.Rate)
+			Value("rate", cr.Rate).
+			Value("closing", cr.Closing).
+			Value("days", cr.Days)
 
 		_, err = s.db.InsertValues(ctx, QuarterlyCurrencyRates, vals, pg.InsertOptions{
-			OnConflict: pg.DoUpdate(3),
+			OnConflict: pg.DoUpdate(3, "fiscal_year", "quarter", "currency_id"),
 		})
 	}
 
 	return s.CommitContext(ctx)
 }
+
+// SetAnnualCurrencyRates implements port.Currency
+func (s currencyStore) SetAnnualCurrencyRates(ctx context.Context, currencyRates []domain.AnnualCurrencyRate) (err error) {
+	fiscalYears := make([]int, len(currencyRates))
+	currencyIds := make([]xid.ID, len(currencyRates))
+	averages := make([]float32, len(currencyRates))
+	closings := make([]*float32, len(currencyRates))
+
+	for i, cr := range currencyRates {
+		fiscalYears[i] = cr.FiscalYear
+		currencyIds[i] = cr.CurrencyID
+		averages[i] = cr.Average
+
+		if cr.Closing.Valid {
+			closings[i] = &cr.Closing.Content
+		}
+	}
+
+	_, err = s.db.Exec(ctx, `
+		insert into %T (fiscal_year, currency_id, average, closing)
+		select r.fiscal_year, r.currency_id, r.average, r.closing
+		from unnest(%c::int[], %c::text[], %c::real[], %c::real[]) r(fiscal_year, currency_id, average, closing)
+		on conflict (fiscal_year, currency_id) do update set
+			average = excluded.average,
+			closing = excluded.closing
+	`, AnnualCurrencyRates, fiscalYears, currencyIds, averages, closings)
+
+	return
+}
+
+// Joins the annual rates of a currency per unit of the base currency as "cr", with "flow" being the
+// rate for income and cash-flow items, and "balance" the rate for balance-sheet items.
+func currencyRates(policy domain.FXPolicy, fiscalYear pg.QueryEncoder, currencyId pg.StringEncoder) pg.QueryEncoder {
+	flow, balance := currencyRateColumns(policy)
+
+	return pg.Raw(`left join lateral (
+			select r.fiscal_year, r.`+flow+` as flow, r.`+balance+` as balance
+			from %T r
+			where r.fiscal_year = %T and r.currency_id = %T
+		) as cr on true`, AnnualCurrencyRates, fiscalYear, currencyId)
+}
+
+// Joins the rates that convert monetary values from the reporting currency to a display currency as
+// "fx", through the base currency and the rates joined as "cr". Both rates are null when any of the
+// currencies lacks rates for the fiscal year, so that values are either all converted or none.
+func currencyConversion(policy domain.FXPolicy, currency string) pg.QueryEncoder {
+	flow, balance := currencyRateColumns(policy)
+
+	rates := pg.Raw(`(
+			select r.`+flow+` as flow, r.`+balance+` as balance
+			from %T r
+			inner join %T rc on rc.id = r.currency_id
+			where rc.name = %c and r.fiscal_year = cr.fiscal_year
+		)`, AnnualCurrencyRates, Currency, currency)
+
+	if currency == domain.BaseCurrency {
+		rates = pg.Raw("(select 1::real as flow, 1::real as balance)")
+	}
+
+	return pg.Raw(`left join lateral %T as dr on true
+		left join lateral (
+			select r.flow, r.balance
+			from (
+				select
+					(case when curr.name = %c then 1 else dr.flow / cr.flow end)::float8 as flow,
+					(case when curr.name = %c then 1 else dr.balance / cr.balance end)::float8 as balance
+			) r
+			where r.flow is not null and r.balance is not null
+		) as fx on true`, rates, currency, currency)
+}
+
+// The columns of annual_currency_rates that flows and balances are converted with.
+func currencyRateColumns(policy domain.FXPolicy) (flow string, balance string) {
+	switch policy {
+	case domain.FXPolicyAverage:
+		return "average", "average"
+	case domain.FXPolicyClosing:
+		return "closing", "closing"
+	}
+
+	return "average", "closing"
+}
+
+// The rate column of a join, e.g. "cr" or "fx", that a metric is converted with.
+func currencyRate(metric domain.Metric, alias string) string {
+	if metric.Balance {
+		return alias + ".balance"
+	}
+
+	return alias + ".flow"
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/webmafia/pg"
+)
+
+func TestCurrencyRateColumns(t *testing.T) {
+	tests := []struct {
+		policy  domain.FXPolicy
+		flow    string
+		balance string
+	}{
+		{policy: domain.FXPolicyStandard, flow: "average", balance: "closing"},
+		{policy: domain.FXPolicyAverage, flow: "average", balance: "average"},
+		{policy: domain.FXPolicyClosing, flow: "closing", balance: "closing"},
+		{policy: "", flow: "average", balance: "closing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			flow, balance := currencyRateColumns(tt.policy)
+
+			if flow != tt.flow || balance != tt.balance {
+				t.Errorf("currencyRateColumns(%q) = %s, %s, want %s, %s", tt.policy, flow, balance, tt.flow, tt.balance)
+			}
+		})
+	}
+}
+
+func TestCurrencyRates(t *testing.T) {
+	tests := []struct {
+		policy domain.FXPolicy
+		want   string
+	}{
+		{policy: domain.FXPolicyStandard, want: "select r.fiscal_year, r.average as flow, r.closing as balance"},
+		{policy: domain.FXPolicyAverage, want: "select r.fiscal_year, r.average as flow, r.average as balance"},
+		{policy: domain.FXPolicyClosing, want: "select r.fiscal_year, r.closing as flow, r.closing as balance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			got, args := encodeQuery(currencyRates(tt.policy, pg.Raw("%c", 2024), Company.Alias("c").Col("currencyId")))
+
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("currencyRates(%q) = %s, want %s", tt.policy, got, tt.want)
+			}
+
+			if !strings.Contains(got, `r.fiscal_year = $1 and r.currency_id = "c"."currencyId"`) {
+				t.Errorf("currencyRates(%q) = %s, want the rates of the company's currency and fiscal year", tt.policy, got)
+			}
+
+			if len(args) != 1 || args[0] != 2024 {
+				t.Errorf("currencyRates(%q) args = %v, want [2024]", tt.policy, args)
+			}
+		})
+	}
+}
+
+func TestCurrencyConversion(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   domain.FXPolicy
+		currency string
+		want     []string
+	}{
+		{
+			name:     "standard",
+			policy:   domain.FXPolicyStandard,
+			currency: "EUR",
+			want: []string{
+				"select r.average as flow, r.closing as balance",
+				"where rc.name = $1 and r.fiscal_year = cr.fiscal_year",
+				"dr.flow / cr.flow",
+				"dr.balance / cr.balance",
+			},
+		},
+		{
+			name:     "average",
+			policy:   domain.FXPolicyAverage,
+			currency: "EUR",
+			want:     []string{"select r.average as flow, r.average as balance"},
+		},
+		{
+			name:     "closing",
+			policy:   domain.FXPolicyClosing,
+			currency: "EUR",
+			want:     []string{"select r.closing as flow, r.closing as balance"},
+		},
+		{
+			name:     "base currency",
+			policy:   domain.FXPolicyStandard,
+			currency: domain.BaseCurrency,
+			want:     []string{"(select 1::real as flow, 1::real as balance) as dr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := encodeQuery(currencyConversion(tt.policy, tt.currency))
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("currencyConversion(%q, %q) = %s, want %s", tt.policy, tt.currency, got, want)
+				}
+			}
+
+			// Values already in the display currency are left as they are
+			if !strings.Contains(got, "case when curr.name = $") {
+				t.Errorf("currencyConversion(%q, %q) = %s, want a rate of 1 for the display currency", tt.policy, tt.currency, got)
+			}
+
+			// Either both rates or none, so that values aren't half converted
+			if !strings.Contains(got, "where r.flow is not null and r.balance is not null") {
+				t.Errorf("currencyConversion(%q, %q) = %s, want both rates or none", tt.policy, tt.currency, got)
+			}
+
+			for _, arg := range args {
+				if arg != tt.currency {
+					t.Errorf("currencyConversion(%q, %q) args = %v, want only the currency", tt.policy, tt.currency, args)
+				}
+			}
+		})
+	}
+}
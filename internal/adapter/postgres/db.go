@@ -17,8 +17,24 @@ type db struct {
 	*pg.DB
 }
 
+// Read-only contexts are repeatable reads, so that all queries in them see the same snapshot, e.g.
+// a count and the page it belongs to.
 func (db db) AcquireContext(ctx context.Context, readOnly ...bool) (newCtx context.Context, err error) {
-	return db.Transaction(ctx, readOnly...)
+	_, nested := ctx.(*pg.Tx)
+	tx, err := db.Transaction(ctx, readOnly...)
+
+	if err != nil {
+		return
+	}
+
+	if len(readOnly) > 0 && readOnly[0] && !nested {
+		if _, err = db.Exec(tx, "set transaction isolation level repeatable read"); err != nil {
+			tx.Release(ctx)
+			return nil, err
+		}
+	}
+
+	return tx, nil
 }
 
 func (db db) CommitContext(ctx context.Context) error {
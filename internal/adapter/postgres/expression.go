@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/webmafia/pg"
+)
+
+// Encodes a parsed filter expression as an SQL condition.
+func screenerExpression(expr domain.FilterExpr) (enc pg.QueryEncoder, err error) {
+	switch e := expr.(type) {
+	case domain.ExprBinary:
+		var left, right pg.QueryEncoder
+
+		if left, err = screenerExpression(e.Left); err != nil {
+			return
+		}
+
+		if right, err = screenerExpression(e.Right); err != nil {
+			return
+		}
+
+		switch e.Op {
+		case domain.ExprOpAnd:
+			return pg.And(left, right), nil
+		case domain.ExprOpOr:
+			return pg.Or(left, right), nil
+		case domain.ExprOpDiv:
+			return pg.Raw("(%T / nullif(%T, 0))", left, right), nil
+		default:
+			return pg.Raw("(%T "+string(e.Op)+" %T)", left, right), nil
+		}
+
+	case domain.ExprNot:
+		if enc, err = screenerExpression(e.Expr); err != nil {
+			return
+		}
+
+		return pg.Raw("not %T", enc), nil
+
+	case domain.ExprNeg:
+		if enc, err = screenerExpression(e.Expr); err != nil {
+			return
+		}
+
+		return pg.Raw("(-%T)", enc), nil
+
+	case domain.ExprIsNull:
+		if enc, err = screenerExpression(e.Expr); err != nil {
+			return
+		}
+
+		if e.Not {
+			return pg.Raw("%T is not null", enc), nil
+		}
+
+		return pg.Raw("%T is null", enc), nil
+
+	case domain.ExprMetric:
+		metric, ok := domain.LookupMetric(domain.MetricID(e.Name))
+
+		if !ok || !metric.Filterable {
+			return nil, domain.ErrUnknownMetric.Detailed("unknown metric \""+e.Name+"\"", "expression")
+		}
+
+		return pg.Raw("%T", screenerValue(metric)), nil
+
+	case domain.ExprNumber:
+		return pg.Raw("%c::float8", e.Value), nil
+	}
+
+	return nil, domain.ErrInvalidExpression.Detailed("unsupported expression", "expression")
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/webmafia/fast"
+	"github.com/webmafia/papi/errors"
+	"github.com/webmafia/pg"
+)
+
+func encodeQuery(q pg.QueryEncoder) (string, []any) {
+	buf := fast.NewStringBuffer(1024)
+	var args []any
+	q.EncodeQuery(buf, &args)
+
+	return buf.String(), args
+}
+
+func TestScreenerExpression(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "pe < 15", want: "((df.pe) < $1::float8)"},
+		{src: "pe / roc > 1", want: "(((df.pe) / nullif((df.roc), 0)) > $1::float8)"},
+		{src: "not pe is not null", want: "not (df.pe) is not null"},
+		{src: "-pe <> 1", want: "((-(df.pe)) != $1::float8)"},
+		{src: "revenue > 100", want: "((f.revenue::float8 / 100 / cr.flow) > $1::float8)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			expr, err := domain.ParseFilterExpression(tt.src)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			enc, err := screenerExpression(expr)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, _ := encodeQuery(enc); got != tt.want {
+				t.Errorf("screenerExpression(%q) = %s, want %s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScreenerExpressionUnknownMetric(t *testing.T) {
+	for _, src := range []string{"foo > 1", "pe < 10 and (bar is null or roc > 0)", "name = 1"} {
+		t.Run(src, func(t *testing.T) {
+			expr, err := domain.ParseFilterExpression(src)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = screenerExpression(expr)
+			e, ok := err.(errors.Error)
+
+			if !ok || e.Code() != "UNKNOWN_METRIC" {
+				t.Errorf("screenerExpression(%q) error = %v, want UNKNOWN_METRIC", src, err)
+			}
+
+			_, _, err = screenerQuery(domain.ScreenerFilter{}, expr, Company.Alias("c"), nil)
+			e, ok = err.(errors.Error)
+
+			if !ok || e.Code() != "UNKNOWN_METRIC" {
+				t.Errorf("screenerQuery(%q) error = %v, want UNKNOWN_METRIC", src, err)
+			}
+		})
+	}
+}
+
+func TestScreenerFilterExpressionWithSearch(t *testing.T) {
+	expr, err := domain.ParseFilterExpression("pe < 15")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cond, err := screenerFilter(domain.ScreenerFilter{Search: "volvo"}, expr)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, args := encodeQuery(cond)
+
+	if !strings.Contains(got, "(df.pe) <") {
+		t.Errorf("screenerFilter() = %s, want the expression applied along with the search", got)
+	}
+
+	if len(args) != 2 {
+		t.Errorf("screenerFilter() args = %v, want the expression value and the search", args)
+	}
+}
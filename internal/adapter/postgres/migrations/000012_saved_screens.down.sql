@@ -0,0 +1 @@
+drop table saved_screens;
\ No newline at end of file
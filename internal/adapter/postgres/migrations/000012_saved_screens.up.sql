@@ -0,0 +1,11 @@
+create table saved_screens (
+    id text primary key,
+    name text not null,
+    description text not null default '',
+    owner text not null default '',
+    visibility text not null default 'private',
+    filter jsonb not null default '{}',
+    created timestamptz not null default now(),
+    updated timestamptz not null default now()
+);
+create index saved_screens_owner on saved_screens(owner);
@@ -0,0 +1,6 @@
+drop table screen_snapshot_members;
+drop table screen_snapshots;
+
+alter table saved_screens
+drop column tracked,
+drop column track_limit;
\ No newline at end of file
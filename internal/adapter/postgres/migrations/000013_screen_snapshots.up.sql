@@ -0,0 +1,25 @@
+alter table saved_screens
+add column tracked boolean not null default false,
+add column track_limit int not null default 100;
+
+create table screen_snapshots (
+    id text primary key,
+    screen_id text not null references saved_screens(id)
+        on update cascade
+        on delete cascade,
+    fiscal_year int not null,
+    created timestamptz not null default now()
+);
+create index screen_snapshots_screen_id on screen_snapshots(screen_id, created);
+
+create table screen_snapshot_members (
+    snapshot_id text not null references screen_snapshots(id)
+        on update cascade
+        on delete cascade,
+    company_id text not null references companies(id)
+        on update cascade
+        on delete cascade,
+    rank bigint not null,
+    primary key (snapshot_id, company_id)
+);
+create index screen_snapshot_members_company_id on screen_snapshot_members(company_id);
\ No newline at end of file
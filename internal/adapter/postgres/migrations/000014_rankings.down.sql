@@ -0,0 +1 @@
+drop table rankings;
\ No newline at end of file
@@ -0,0 +1,8 @@
+create table rankings (
+    id text primary key,
+    name text not null,
+    description text not null default '',
+    factors jsonb not null default '[]',
+    created timestamptz not null default now(),
+    updated timestamptz not null default now()
+);
\ No newline at end of file
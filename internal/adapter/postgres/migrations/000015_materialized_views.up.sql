@@ -0,0 +1,106 @@
+drop view derived_financials;
+create materialized view derived_financials as
+
+with calcs as (
+	select
+		f.company_id,
+		f.fiscal_year,
+		f.net_income::float * 10000 / nullif(f.number_of_shares, 0) as eps,
+		f.number_of_shares * s.average::float / 1000000 / nullif(f.net_income, 0) as pe,
+		(f.number_of_shares * s.average::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float) / nullif(f.ebit, 0) AS evebit,
+		f.number_of_shares * s.average::float / 1000000 / nullif(f.revenue, 0) as ps,
+		f.number_of_shares * s.average::float / 1000000 / nullif((f.total_assets-f.total_liabilities), 0) as pb,
+
+        f.ebit::float / nullif(f.revenue, 0)::float as operating_margin,
+        f.ebit::float / nullif(f.net_income, 0)::float as net_margin,
+        f.net_income::float / nullif(f.equity, 0)::float as roe,
+        f.ebit::float / nullif(f.ppe + f.total_assets - f.total_liabilities, 0)::float as roc,
+        f.total_liabilities::float / nullif(f.equity, 0)::float as liabilities_to_equity,
+        (f.long_term_debt + f.current_debt - f.cash_and_equivalents)::float / nullif(f.ebit, 0)::float as debt_to_ebit,
+        (f.long_term_debt + f.current_debt)::float / nullif(f.total_assets, 0)::float as debt_to_assets,
+        f.operating_cash_flow::float / nullif(f.net_income, 0)::float as cash_conversion
+	from financials f
+	
+	inner join shares s on s.company_id = f.company_id and s.date = TO_DATE((f.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD')
+)
+select 
+    c.company_id, 
+    c.fiscal_year, 
+    c.eps, 
+    c.pe, 
+    c.evebit, 
+    c.ps, 
+    c.pb,
+    c.operating_margin,
+    c.net_margin,
+    c.roe,
+    c.roc,
+    c.liabilities_to_equity,
+    c.debt_to_ebit,
+    c.debt_to_assets,
+    c.cash_conversion
+from calcs c;
+create unique index derived_financials_company_id_fiscal_year on derived_financials(company_id, fiscal_year);
+create index derived_financials_fiscal_year on derived_financials(fiscal_year);
+
+drop view magic_formula_rankings;
+create materialized view magic_formula_rankings as
+
+WITH
+	calcs AS (
+		SELECT
+			f.company_id,
+			f.fiscal_year,
+			f.ebit::numeric / (f.ppe + f.total_assets - f.total_liabilities) AS roc,
+			f.ebit / ((f.number_of_shares * s.average + f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::numeric / 1000000) AS yield
+		FROM
+			financials f
+			INNER JOIN shares s ON f.company_id = s.company_id
+			AND s.date = TO_DATE((f.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD')
+		WHERE
+			s.average > 0
+	),
+	ranks AS (
+		SELECT
+			c.company_id,
+			c.fiscal_year,
+			c.roc,
+			c.yield,
+			rank() OVER (
+				PARTITION BY
+					c.fiscal_year
+				ORDER BY
+					c.roc DESC
+			) AS roc_rank,
+			rank() OVER (
+				PARTITION BY
+					c.fiscal_year
+				ORDER BY
+					c.yield DESC
+			) AS yield_rank
+		FROM
+			calcs c
+	)
+SELECT
+	r.company_id,
+	r.fiscal_year,
+	r.roc,
+	r.yield,
+	r.roc_rank,
+	r.yield_rank,
+	rank() OVER (
+		PARTITION BY
+			r.fiscal_year
+		ORDER BY
+			r.roc_rank + r.yield_rank ASC
+	) AS RANK
+FROM
+	ranks r;
+create unique index magic_formula_rankings_company_id_fiscal_year on magic_formula_rankings(company_id, fiscal_year);
+create index magic_formula_rankings_fiscal_year_rank on magic_formula_rankings(fiscal_year, rank);
+
+create table view_refreshes (
+    name text primary key,
+    refreshed timestamptz not null default now()
+);
+insert into view_refreshes (name) values ('derived_financials'), ('magic_formula_rankings');
\ No newline at end of file
@@ -0,0 +1,5 @@
+drop table annual_currency_rates;
+
+alter table quarterly_currency_rates
+    drop column closing,
+    drop column days;
\ No newline at end of file
@@ -0,0 +1,25 @@
+alter table quarterly_currency_rates
+    add column closing real,
+    add column days smallint;
+
+-- Rates for a whole fiscal year: the average of the daily rates, and the closing rate of the last
+-- quarter with rates, i.e. the fourth quarter once the year has ended. They are aggregated from the
+-- quarterly rates whenever those are fetched.
+create table annual_currency_rates(
+    fiscal_year int not null,
+    currency_id text not null references currencies(id)
+        on update cascade
+        on delete cascade,
+    average real not null,
+    closing real,
+    primary key (fiscal_year, currency_id)
+);
+
+insert into annual_currency_rates (fiscal_year, currency_id, average, closing)
+select
+    r.fiscal_year,
+    r.currency_id,
+    coalesce(sum(r.rate * r.days) / nullif(sum(r.days), 0), avg(r.rate))::real,
+    nullif((array_agg(r.closing order by r.quarter desc))[1], 0)
+from quarterly_currency_rates r
+group by r.fiscal_year, r.currency_id;
\ No newline at end of file
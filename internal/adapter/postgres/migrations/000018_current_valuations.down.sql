@@ -0,0 +1,3 @@
+delete from view_refreshes where name = 'current_valuations';
+
+drop materialized view current_valuations;
\ No newline at end of file
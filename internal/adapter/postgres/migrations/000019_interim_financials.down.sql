@@ -0,0 +1,4 @@
+delete from view_refreshes where name = 'ttm_financials';
+
+drop materialized view ttm_financials;
+drop table interim_financials;
\ No newline at end of file
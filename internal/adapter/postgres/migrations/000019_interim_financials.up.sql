@@ -0,0 +1,102 @@
+create table interim_financials (
+    company_id text not null references companies(id)
+        on update cascade
+        on delete cascade,
+    fiscal_year int not null,
+    period text not null check (period in ('q1', 'q2', 'q3', 'q4', 'h1', 'h2')),
+
+    currency text not null references currencies(id),
+
+    -- income statement
+    revenue int,
+    cost_of_revenue int,
+    gross_operating_profit int,
+    ebit int,
+    net_income int,
+
+    -- balance sheet, at the end of the period
+    total_assets int,
+    total_liabilities int,
+    cash_and_equivalents int,
+    short_term_investments int,
+    long_term_debt int,
+    current_debt int,
+    equity int,
+    number_of_shares bigint default 0,
+    ppe int not null default 0,
+
+    -- cash flow
+    operating_cash_flow int,
+    capital_expenditures int,
+    free_cash_flow int,
+
+    primary key (company_id, fiscal_year, period)
+);
+
+-- Trailing twelve months up until each company's latest interim period: flow items are summed over
+-- the last four quarters or two half-years, and balance-sheet items are those of the latest period.
+-- Companies without a full year of consecutive periods are left out, and a flow item that's missing
+-- from any of the periods is null rather than summed over the others.
+create materialized view ttm_financials as
+
+with
+    periods as (
+        select
+            i.*,
+            case when i.period like 'q%' then 4 else 2 end as per_year,
+            substr(i.period, 2)::int as n
+        from interim_financials i
+    ),
+    latest as (
+        select distinct on (p.company_id)
+            p.*,
+            p.fiscal_year * p.per_year + p.n as seq
+        from periods p
+        -- By the month the period ends in, preferring quarters over half-years
+        order by p.company_id, p.fiscal_year * 12 + p.n * 12 / p.per_year desc, p.per_year desc
+    ),
+    flows as (
+        select
+            l.company_id,
+            count(*) as periods,
+            (case when count(p.revenue) = l.per_year then sum(p.revenue) end)::int as revenue,
+            (case when count(p.cost_of_revenue) = l.per_year then sum(p.cost_of_revenue) end)::int as cost_of_revenue,
+            (case when count(p.gross_operating_profit) = l.per_year then sum(p.gross_operating_profit) end)::int as gross_operating_profit,
+            (case when count(p.ebit) = l.per_year then sum(p.ebit) end)::int as ebit,
+            (case when count(p.net_income) = l.per_year then sum(p.net_income) end)::int as net_income,
+            (case when count(p.operating_cash_flow) = l.per_year then sum(p.operating_cash_flow) end)::int as operating_cash_flow,
+            (case when count(p.capital_expenditures) = l.per_year then sum(p.capital_expenditures) end)::int as capital_expenditures,
+            (case when count(p.free_cash_flow) = l.per_year then sum(p.free_cash_flow) end)::int as free_cash_flow
+        from latest l
+        inner join periods p on p.company_id = l.company_id
+            and p.per_year = l.per_year
+            and p.fiscal_year * p.per_year + p.n between l.seq - l.per_year + 1 and l.seq
+        group by l.company_id, l.per_year
+    )
+select
+    l.company_id,
+    l.fiscal_year,
+    l.period,
+    l.currency,
+    fl.revenue,
+    fl.cost_of_revenue,
+    fl.gross_operating_profit,
+    fl.ebit,
+    fl.net_income,
+    l.total_assets,
+    l.total_liabilities,
+    l.cash_and_equivalents,
+    l.short_term_investments,
+    l.long_term_debt,
+    l.current_debt,
+    l.equity,
+    l.number_of_shares,
+    l.ppe,
+    fl.operating_cash_flow,
+    fl.capital_expenditures,
+    fl.free_cash_flow
+from latest l
+inner join flows fl on fl.company_id = l.company_id and fl.periods = l.per_year;
+create unique index ttm_financials_company_id on ttm_financials(company_id);
+
+insert into view_refreshes (name) values ('ttm_financials');
\ No newline at end of file
@@ -0,0 +1,7 @@
+delete from view_refreshes where name in ('dividend_metrics', 'total_returns');
+
+drop materialized view total_returns;
+drop materialized view dividend_metrics;
+drop view company_dividends;
+drop view trading_currencies;
+drop table dividends;
\ No newline at end of file
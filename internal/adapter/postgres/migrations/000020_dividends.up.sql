@@ -0,0 +1,125 @@
+create table dividends (
+    company_id text not null references companies(id)
+        on update cascade
+        on delete cascade,
+    ex_date date not null,
+    amount int not null, -- per share, in the same precision as share prices
+    currency text not null references currencies(id),
+    primary key (company_id, ex_date)
+);
+
+-- The currency that the shares of each company trade in, which is that of its marketplace. Companies
+-- without a known marketplace are assumed to trade in their reporting currency.
+create view trading_currencies as
+select
+    c.id as company_id,
+    coalesce(curr.id, c."currencyId") as currency_id
+from companies c
+left join currencies curr on curr.name = case c.market_place_code
+    when 'xsto' then 'SEK'
+    when 'xcse' then 'DKK'
+    when 'xhel' then 'EUR'
+    when 'xice' then 'ISK'
+end;
+
+-- Dividends in the currency that the shares trade in, so that they're comparable with share prices,
+-- and in the reporting currency of the company, so that they're comparable with financials. Both are
+-- converted at the average rate of the year.
+create view company_dividends as
+select
+    d.company_id,
+    d.ex_date,
+    case
+        when d.currency = tc.currency_id then d.amount::float8
+        else d.amount * tr.average::float8 / nullif(dr.average, 0)
+    end as amount,
+    case
+        when d.currency = c."currencyId" then d.amount::float8
+        else d.amount * cr.average::float8 / nullif(dr.average, 0)
+    end as reporting_amount
+from dividends d
+inner join companies c on c.id = d.company_id
+inner join trading_currencies tc on tc.company_id = d.company_id
+left join annual_currency_rates dr on dr.currency_id = d.currency and dr.fiscal_year = extract(year from d.ex_date)::int
+left join annual_currency_rates tr on tr.currency_id = tc.currency_id and tr.fiscal_year = extract(year from d.ex_date)::int
+left join annual_currency_rates cr on cr.currency_id = c."currencyId" and cr.fiscal_year = extract(year from d.ex_date)::int;
+
+-- Dividends paid during each fiscal year, for companies with any dividends at all
+create materialized view dividend_metrics as
+
+with
+    annual as (
+        select
+            f.company_id,
+            f.fiscal_year,
+            f.net_income,
+            f.number_of_shares,
+            coalesce(sum(d.amount), 0) as dps,
+            coalesce(sum(d.reporting_amount), 0) as reporting_dps
+        from financials f
+        left join company_dividends d on d.company_id = f.company_id and extract(year from d.ex_date)::int = f.fiscal_year
+        where f.company_id in (select company_id from dividends)
+        group by f.company_id, f.fiscal_year, f.net_income, f.number_of_shares
+    ),
+    growth as (
+        select
+            a.*,
+            coalesce(a.dps > lag(a.dps) over w and lag(a.fiscal_year) over w = a.fiscal_year - 1, false) as grew
+        from annual a
+        window w as (partition by a.company_id order by a.fiscal_year)
+    ),
+    streaks as (
+        select
+            g.*,
+            count(*) filter (where not g.grew) over (partition by g.company_id order by g.fiscal_year) as streak
+        from growth g
+    )
+select
+    s.company_id,
+    s.fiscal_year,
+    s.dps / 100 as dividends_per_share,
+    s.dps / nullif(sh.average, 0) as dividend_yield,
+    s.reporting_dps * s.number_of_shares / 1000000 / nullif(s.net_income, 0) as payout_ratio,
+    row_number() over (partition by s.company_id, s.streak order by s.fiscal_year) - 1 as growth_years
+from streaks s
+left join shares sh on sh.company_id = s.company_id and sh.date = to_date((s.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD');
+create unique index dividend_metrics_company_id_fiscal_year on dividend_metrics(company_id, fiscal_year);
+
+-- Closing prices with dividends reinvested on their ex-dates, indexed to the first close so that
+-- they're in the same precision as share prices
+create materialized view total_returns as
+
+with
+    prices as (
+        select
+            s.company_id,
+            s.date,
+            s.close,
+            lag(s.close) over w as previous_close,
+            lag(s.date) over w as previous_date
+        from shares s
+        window w as (partition by s.company_id order by s.date)
+    ),
+    returns as (
+        select
+            p.*,
+            coalesce((
+                select sum(d.amount)
+                from company_dividends d
+                where d.company_id = p.company_id and d.ex_date > p.previous_date and d.ex_date <= p.date
+            ), 0) as dividend
+        from prices p
+    )
+select
+    r.company_id,
+    r.date,
+    r.close,
+    r.dividend,
+    first_value(r.close) over w * exp(sum(
+        case when r.previous_close > 0 and r.close + r.dividend > 0 then ln((r.close + r.dividend) / r.previous_close::float8) else 0 end
+    ) over w) as total_return
+from returns r
+window w as (partition by r.company_id order by r.date);
+create unique index total_returns_company_id_date on total_returns(company_id, date);
+
+insert into view_refreshes (name) values ('dividend_metrics'), ('total_returns');
\ No newline at end of file
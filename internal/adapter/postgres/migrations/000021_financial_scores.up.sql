@@ -0,0 +1,151 @@
+alter table financials
+    -- balance sheet
+    add column current_assets int,
+    add column current_liabilities int,
+    add column retained_earnings int,
+    add column receivables int,
+
+    -- income statement
+    add column depreciation int,
+    add column sga int;
+
+alter table interim_financials
+    -- balance sheet
+    add column current_assets int,
+    add column current_liabilities int,
+    add column retained_earnings int,
+    add column receivables int,
+
+    -- income statement
+    add column depreciation int,
+    add column sga int;
+
+drop materialized view ttm_financials;
+
+-- Trailing twelve months up until each company's latest interim period: flow items are summed over
+-- the last four quarters or two half-years, and balance-sheet items are those of the latest period.
+-- Companies without a full year of consecutive periods are left out, and a flow item that's missing
+-- from any of the periods is null rather than summed over the others.
+create materialized view ttm_financials as
+
+with
+    periods as (
+        select
+            i.*,
+            case when i.period like 'q%' then 4 else 2 end as per_year,
+            substr(i.period, 2)::int as n
+        from interim_financials i
+    ),
+    latest as (
+        select distinct on (p.company_id)
+            p.*,
+            p.fiscal_year * p.per_year + p.n as seq
+        from periods p
+        -- By the month the period ends in, preferring quarters over half-years
+        order by p.company_id, p.fiscal_year * 12 + p.n * 12 / p.per_year desc, p.per_year desc
+    ),
+    flows as (
+        select
+            l.company_id,
+            count(*) as periods,
+            (case when count(p.revenue) = l.per_year then sum(p.revenue) end)::int as revenue,
+            (case when count(p.cost_of_revenue) = l.per_year then sum(p.cost_of_revenue) end)::int as cost_of_revenue,
+            (case when count(p.gross_operating_profit) = l.per_year then sum(p.gross_operating_profit) end)::int as gross_operating_profit,
+            (case when count(p.ebit) = l.per_year then sum(p.ebit) end)::int as ebit,
+            (case when count(p.net_income) = l.per_year then sum(p.net_income) end)::int as net_income,
+            (case when count(p.operating_cash_flow) = l.per_year then sum(p.operating_cash_flow) end)::int as operating_cash_flow,
+            (case when count(p.capital_expenditures) = l.per_year then sum(p.capital_expenditures) end)::int as capital_expenditures,
+            (case when count(p.free_cash_flow) = l.per_year then sum(p.free_cash_flow) end)::int as free_cash_flow,
+            (case when count(p.depreciation) = l.per_year then sum(p.depreciation) end)::int as depreciation,
+            (case when count(p.sga) = l.per_year then sum(p.sga) end)::int as sga
+        from latest l
+        inner join periods p on p.company_id = l.company_id
+            and p.per_year = l.per_year
+            and p.fiscal_year * p.per_year + p.n between l.seq - l.per_year + 1 and l.seq
+        group by l.company_id, l.per_year
+    )
+select
+    l.company_id,
+    l.fiscal_year,
+    l.period,
+    l.currency,
+    fl.revenue,
+    fl.cost_of_revenue,
+    fl.gross_operating_profit,
+    fl.ebit,
+    fl.net_income,
+    l.total_assets,
+    l.total_liabilities,
+    l.cash_and_equivalents,
+    l.short_term_investments,
+    l.long_term_debt,
+    l.current_debt,
+    l.equity,
+    l.number_of_shares,
+    l.ppe,
+    fl.operating_cash_flow,
+    fl.capital_expenditures,
+    fl.free_cash_flow,
+    l.current_assets,
+    l.current_liabilities,
+    l.retained_earnings,
+    l.receivables,
+    fl.depreciation,
+    fl.sga
+from latest l
+inner join flows fl on fl.company_id = l.company_id and fl.periods = l.per_year;
+create unique index ttm_financials_company_id on ttm_financials(company_id);
+
+-- Piotroski F-score, Altman Z-score and Beneish M-score of each fiscal year, along with their
+-- components. Piotroski and Beneish compare against the previous fiscal year. A missing input makes
+-- its component, and thereby the score, null rather than computed from zeros.
+create materialized view financial_scores as
+
+with
+    components as (
+        select
+            f.company_id,
+            f.fiscal_year,
+
+            -- Piotroski signals, 1 if passed
+            (f.net_income > 0)::int as f_roa,
+            (f.operating_cash_flow > 0)::int as f_cfo,
+            (f.net_income::float / nullif(f.total_assets, 0) > p.net_income::float / nullif(p.total_assets, 0))::int as f_delta_roa,
+            (f.operating_cash_flow > f.net_income)::int as f_accruals,
+            (f.long_term_debt::float / nullif(f.total_assets, 0) < p.long_term_debt::float / nullif(p.total_assets, 0))::int as f_delta_leverage,
+            (f.current_assets::float / nullif(f.current_liabilities, 0) > p.current_assets::float / nullif(p.current_liabilities, 0))::int as f_delta_liquidity,
+            -- Share counts are the current ones in every fiscal year, so dilution is unknown until there are
+            -- share counts per year, which leaves the F-score incomplete
+            null::int as f_no_dilution,
+            (f.gross_operating_profit::float / nullif(f.revenue, 0) > p.gross_operating_profit::float / nullif(p.revenue, 0))::int as f_delta_margin,
+            (f.revenue::float / nullif(f.total_assets, 0) > p.revenue::float / nullif(p.total_assets, 0))::int as f_delta_turnover,
+
+            -- Altman ratios
+            (f.current_assets - f.current_liabilities)::float / nullif(f.total_assets, 0) as z_working_capital,
+            f.retained_earnings::float / nullif(f.total_assets, 0) as z_retained_earnings,
+            f.ebit::float / nullif(f.total_assets, 0) as z_ebit,
+            df.market_cap::float / nullif(f.total_liabilities, 0) as z_market_value,
+            f.revenue::float / nullif(f.total_assets, 0) as z_sales,
+
+            -- Beneish indices
+            (f.receivables::float / nullif(f.revenue, 0)) / nullif(p.receivables::float / nullif(p.revenue, 0), 0) as m_dsri,
+            (p.gross_operating_profit::float / nullif(p.revenue, 0)) / nullif(f.gross_operating_profit::float / nullif(f.revenue, 0), 0) as m_gmi,
+            (1 - (f.current_assets + f.ppe)::float / nullif(f.total_assets, 0)) / nullif(1 - (p.current_assets + p.ppe)::float / nullif(p.total_assets, 0), 0) as m_aqi,
+            f.revenue::float / nullif(p.revenue, 0) as m_sgi,
+            (p.depreciation::float / nullif(p.depreciation + p.ppe, 0)) / nullif(f.depreciation::float / nullif(f.depreciation + f.ppe, 0), 0) as m_depi,
+            (f.sga::float / nullif(f.revenue, 0)) / nullif(p.sga::float / nullif(p.revenue, 0), 0) as m_sgai,
+            (f.net_income - f.operating_cash_flow)::float / nullif(f.total_assets, 0) as m_tata,
+            ((f.current_liabilities + f.long_term_debt)::float / nullif(f.total_assets, 0)) / nullif((p.current_liabilities + p.long_term_debt)::float / nullif(p.total_assets, 0), 0) as m_lvgi
+        from financials f
+        left join financials p on p.company_id = f.company_id and p.fiscal_year = f.fiscal_year - 1
+        left join derived_financials df on df.company_id = f.company_id and df.fiscal_year = f.fiscal_year
+    )
+select
+    c.*,
+    c.f_roa + c.f_cfo + c.f_delta_roa + c.f_accruals + c.f_delta_leverage + c.f_delta_liquidity + c.f_no_dilution + c.f_delta_margin + c.f_delta_turnover as f_score,
+    1.2 * c.z_working_capital + 1.4 * c.z_retained_earnings + 3.3 * c.z_ebit + 0.6 * c.z_market_value + 1.0 * c.z_sales as z_score,
+    -4.84 + 0.92 * c.m_dsri + 0.528 * c.m_gmi + 0.404 * c.m_aqi + 0.892 * c.m_sgi + 0.115 * c.m_depi - 0.172 * c.m_sgai + 4.679 * c.m_tata - 0.327 * c.m_lvgi as m_score
+from components c;
+create unique index financial_scores_company_id_fiscal_year on financial_scores(company_id, fiscal_year);
+
+insert into view_refreshes (name) values ('financial_scores');
\ No newline at end of file
@@ -0,0 +1,3 @@
+delete from view_refreshes where name = 'price_metrics';
+
+drop materialized view price_metrics;
\ No newline at end of file
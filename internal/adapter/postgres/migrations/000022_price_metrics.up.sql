@@ -0,0 +1,137 @@
+-- Momentum, volatility and liquidity of each company's share price, as of the valuation date of each
+-- fiscal year (January 2 of the following year, as in derived_financials) and as of the latest price.
+-- Windows are in trading days, and a metric is null unless its whole window has prices.
+create materialized view price_metrics as
+
+with
+    anchors as (
+        select
+            s.company_id,
+            extract(year from s.date)::int - 1 as fiscal_year,
+            s.date,
+            s.close,
+            false as current
+        from shares s
+        where to_char(s.date, 'MM-DD') = '01-02'
+
+        union all
+
+        select distinct on (s.company_id)
+            s.company_id,
+            extract(year from s.date)::int - 1 as fiscal_year,
+            s.date,
+            s.close,
+            true as current
+        from shares s
+        order by s.company_id, s.date desc
+    )
+select
+    a.company_id,
+    a.fiscal_year,
+    a.date,
+    a.current,
+
+    a.close::float / nullif((select s.close from shares s where s.company_id = a.company_id and s.date <= a.date - interval '1 month' order by s.date desc limit 1), 0) - 1 as return_1m,
+    a.close::float / nullif((select s.close from shares s where s.company_id = a.company_id and s.date <= a.date - interval '3 months' order by s.date desc limit 1), 0) - 1 as return_3m,
+    a.close::float / nullif((select s.close from shares s where s.company_id = a.company_id and s.date <= a.date - interval '6 months' order by s.date desc limit 1), 0) - 1 as return_6m,
+    a.close::float / nullif((select s.close from shares s where s.company_id = a.company_id and s.date <= a.date - interval '12 months' order by s.date desc limit 1), 0) - 1 as return_12m,
+
+    a.close::float / nullif(y.high, 0) - 1 as high_52w_distance,
+    a.close::float / nullif(y.low, 0) - 1 as low_52w_distance,
+
+    a.close::float / nullif(ma50.average, 0) - 1 as ma50_position,
+    a.close::float / nullif(ma200.average, 0) - 1 as ma200_position,
+
+    rsi.rsi,
+    vol.volatility,
+
+    -- In the same precision and currency as the financials
+    tv.traded_value * tcr.rate as traded_value
+from anchors a
+
+left join lateral (
+    select
+        max(s.high) as high,
+        min(s.low) as low
+    from shares s
+    where s.company_id = a.company_id and s.date > a.date - interval '1 year' and s.date <= a.date
+) y on true
+
+left join lateral (
+    select case when count(*) = 50 then avg(t.close) end as average
+    from (
+        select s.close
+        from shares s
+        where s.company_id = a.company_id and s.date <= a.date
+        order by s.date desc
+        limit 50
+    ) t
+) ma50 on true
+
+left join lateral (
+    select case when count(*) = 200 then avg(t.close) end as average
+    from (
+        select s.close
+        from shares s
+        where s.company_id = a.company_id and s.date <= a.date
+        order by s.date desc
+        limit 200
+    ) t
+) ma200 on true
+
+-- 14-day relative strength index, by simple averages of the gains and losses
+left join lateral (
+    select case when count(t.change) = 14 then 100 * sum(greatest(t.change, 0)) / nullif(sum(abs(t.change)), 0) end as rsi
+    from (
+        select t.close - lag(t.close) over (order by t.date) as change
+        from (
+            select s.date, s.close
+            from shares s
+            where s.company_id = a.company_id and s.date <= a.date
+            order by s.date desc
+            limit 15
+        ) t
+    ) t
+) rsi on true
+
+-- Annualized standard deviation of the daily log returns over a year
+left join lateral (
+    select case when count(t.r) = 252 then stddev_samp(t.r) * sqrt(252) end as volatility
+    from (
+        select ln(t.close::float / nullif(lag(t.close) over (order by t.date), 0)) as r
+        from (
+            select s.date, s.close
+            from shares s
+            where s.company_id = a.company_id and s.date <= a.date and s.close > 0
+            order by s.date desc
+            limit 253
+        ) t
+    ) t
+) vol on true
+
+-- Average daily traded value over three months
+left join lateral (
+    select avg(s.close::float * s.volume / 100000000) as traded_value
+    from shares s
+    where s.company_id = a.company_id and s.date > a.date - interval '3 months' and s.date <= a.date
+) tv on true
+
+-- Rate from the trading currency to the reporting currency, of the latest fiscal year with rates as
+-- of the date, so that the traded value is comparable with the financials
+left join lateral (
+    select
+        case
+            when tc.currency_id = c."currencyId" then 1
+            else cr.average::float8 / nullif(tr.average, 0)
+        end as rate
+    from companies c
+    inner join trading_currencies tc on tc.company_id = c.id
+    left join annual_currency_rates tr on tr.currency_id = tc.currency_id and tr.fiscal_year <= extract(year from a.date)::int
+    left join annual_currency_rates cr on cr.currency_id = c."currencyId" and cr.fiscal_year = tr.fiscal_year
+    where c.id = a.company_id
+    order by tr.fiscal_year desc nulls last
+    limit 1
+) tcr on true;
+create unique index price_metrics_company_id_fiscal_year_current on price_metrics(company_id, fiscal_year, current);
+
+insert into view_refreshes (name) values ('price_metrics');
\ No newline at end of file
@@ -0,0 +1,2 @@
+drop view adjusted_shares;
+drop table splits;
\ No newline at end of file
@@ -0,0 +1,27 @@
+create table splits (
+    company_id text not null references companies(id)
+        on update cascade
+        on delete cascade,
+    date timestamp not null, -- The first trading day after the split
+    ratio real not null check (ratio > 0), -- New shares per old share, e.g. 2 for a 2:1 split and 0.1 for a 1:10 reverse split
+    primary key (company_id, date)
+);
+
+-- Share prices and volumes adjusted for all later splits, so that they're comparable with the latest
+-- prices. Prices are in the same precision as in shares.
+create view adjusted_shares as
+select
+    s.company_id,
+    s.date,
+    s.open / f.factor as open,
+    s.high / f.factor as high,
+    s.low / f.factor as low,
+    s.close / f.factor as close,
+    round(s.volume * f.factor)::bigint as volume,
+    s.average / f.factor as average
+from shares s
+cross join lateral (
+    select coalesce(exp(sum(ln(sp.ratio))), 1) as factor
+    from splits sp
+    where sp.company_id = s.company_id and sp.date > s.date
+) f;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table price_ingestions;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- The latest daily price ingestion of each company, so that a run can resume where the last one left off
+create table price_ingestions (
+    company_id text primary key references companies(id)
+        on update cascade
+        on delete cascade,
+    attempted timestamp not null,
+    succeeded timestamp, -- The latest successful ingestion
+    error text not null default '' -- Of the latest attempt, empty if it succeeded
+);
\ No newline at end of file
@@ -0,0 +1,491 @@
+drop materialized view financial_scores;
+drop materialized view derived_financials;
+drop materialized view current_valuations;
+drop materialized view magic_formula_rankings;
+drop materialized view dividend_metrics;
+drop materialized view total_returns;
+drop materialized view price_metrics;
+drop view company_dividends;
+drop view adjusted_shares;
+
+create table corporate_actions (
+    company_id text not null references companies(id)
+        on update cascade
+        on delete cascade,
+    date timestamp not null, -- The first trading day after the action
+    kind text not null check (kind in ('split', 'reverse_split', 'bonus_issue', 'spin_off')),
+
+    -- The factor that earlier prices and dividends are divided by. For splits, reverse splits and bonus
+    -- issues it's also the new shares per old share, which earlier volumes are multiplied by. Share
+    -- counts in financials are the current ones and aren't adjusted.
+    ratio real not null check (ratio > 0),
+    note text not null default '',
+    primary key (company_id, date, kind)
+);
+
+insert into corporate_actions (company_id, date, kind, ratio)
+select
+    sp.company_id,
+    sp.date,
+    case when sp.ratio < 1 then 'reverse_split' else 'split' end,
+    sp.ratio
+from splits sp;
+
+drop table splits;
+
+-- Share prices and volumes adjusted for all later corporate actions, so that they're comparable with
+-- the latest prices. Prices are in the same precision as in shares.
+create view adjusted_shares as
+select
+    s.company_id,
+    s.date,
+    s.open / a.price as open,
+    s.high / a.price as high,
+    s.low / a.price as low,
+    s.close / a.price as close,
+    round(s.volume * a.shares)::bigint as volume,
+    s.average / a.price as average
+from shares s
+cross join lateral (
+    select
+        coalesce(exp(sum(ln(ca.ratio))), 1) as price,
+        coalesce(exp(sum(ln(ca.ratio)) filter (where ca.kind <> 'spin_off')), 1) as shares
+    from corporate_actions ca
+    where ca.company_id = s.company_id and ca.date > s.date
+) a;
+
+-- Dividends in the currency that the shares trade in, so that they're comparable with share prices,
+-- and in the reporting currency of the company, so that they're comparable with financials. Both are
+-- converted at the average rate of the year, and adjusted for all later corporate actions.
+create view company_dividends as
+select
+    d.company_id,
+    d.ex_date,
+    case
+        when d.currency = tc.currency_id then d.amount::float8
+        else d.amount * tr.average::float8 / nullif(dr.average, 0)
+    end / a.price as amount,
+    case
+        when d.currency = c."currencyId" then d.amount::float8
+        else d.amount * cr.average::float8 / nullif(dr.average, 0)
+    end / a.price as reporting_amount
+from dividends d
+inner join companies c on c.id = d.company_id
+inner join trading_currencies tc on tc.company_id = d.company_id
+left join annual_currency_rates dr on dr.currency_id = d.currency and dr.fiscal_year = extract(year from d.ex_date)::int
+left join annual_currency_rates tr on tr.currency_id = tc.currency_id and tr.fiscal_year = extract(year from d.ex_date)::int
+left join annual_currency_rates cr on cr.currency_id = c."currencyId" and cr.fiscal_year = extract(year from d.ex_date)::int
+cross join lateral (
+    select coalesce(exp(sum(ln(ca.ratio))), 1) as price
+    from corporate_actions ca
+    where ca.company_id = d.company_id and ca.date > d.ex_date
+) a;
+
+create materialized view derived_financials as
+
+with calcs as (
+	select
+		f.company_id,
+		f.fiscal_year,
+		f.net_income::float * 10000 / nullif(f.number_of_shares, 0) as eps,
+		f.number_of_shares * s.average::float / 1000000 / nullif(f.net_income, 0) as pe,
+		(f.number_of_shares * s.average::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float) / nullif(f.ebit, 0) AS evebit,
+		f.number_of_shares * s.average::float / 1000000 / nullif(f.revenue, 0) as ps,
+		f.number_of_shares * s.average::float / 1000000 / nullif((f.total_assets-f.total_liabilities), 0) as pb,
+
+        f.ebit::float / nullif(f.revenue, 0)::float as operating_margin,
+        f.ebit::float / nullif(f.net_income, 0)::float as net_margin,
+        f.net_income::float / nullif(f.equity, 0)::float as roe,
+        f.ebit::float / nullif(f.ppe + f.total_assets - f.total_liabilities, 0)::float as roc,
+        f.total_liabilities::float / nullif(f.equity, 0)::float as liabilities_to_equity,
+        (f.long_term_debt + f.current_debt - f.cash_and_equivalents)::float / nullif(f.ebit, 0)::float as debt_to_ebit,
+        (f.long_term_debt + f.current_debt)::float / nullif(f.total_assets, 0)::float as debt_to_assets,
+        f.operating_cash_flow::float / nullif(f.net_income, 0)::float as cash_conversion,
+
+        -- In the same precision as the financials
+        round(f.number_of_shares * s.average::float / 1000000)::bigint as market_cap,
+        round(f.number_of_shares * s.average::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float)::bigint as enterprise_value
+	from financials f
+	
+	inner join adjusted_shares s on s.company_id = f.company_id and s.date = TO_DATE((f.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD')
+)
+select 
+    c.company_id, 
+    c.fiscal_year, 
+    c.eps, 
+    c.pe, 
+    c.evebit, 
+    c.ps, 
+    c.pb,
+    c.operating_margin,
+    c.net_margin,
+    c.roe,
+    c.roc,
+    c.liabilities_to_equity,
+    c.debt_to_ebit,
+    c.debt_to_assets,
+    c.cash_conversion,
+    c.market_cap,
+    c.enterprise_value
+from calcs c;
+create unique index derived_financials_company_id_fiscal_year on derived_financials(company_id, fiscal_year);
+create index derived_financials_fiscal_year on derived_financials(fiscal_year);
+
+-- Piotroski F-score, Altman Z-score and Beneish M-score of each fiscal year, along with their
+-- components. Piotroski and Beneish compare against the previous fiscal year. A missing input makes
+-- its component, and thereby the score, null rather than computed from zeros.
+create materialized view financial_scores as
+
+with
+    components as (
+        select
+            f.company_id,
+            f.fiscal_year,
+
+            -- Piotroski signals, 1 if passed
+            (f.net_income > 0)::int as f_roa,
+            (f.operating_cash_flow > 0)::int as f_cfo,
+            (f.net_income::float / nullif(f.total_assets, 0) > p.net_income::float / nullif(p.total_assets, 0))::int as f_delta_roa,
+            (f.operating_cash_flow > f.net_income)::int as f_accruals,
+            (f.long_term_debt::float / nullif(f.total_assets, 0) < p.long_term_debt::float / nullif(p.total_assets, 0))::int as f_delta_leverage,
+            (f.current_assets::float / nullif(f.current_liabilities, 0) > p.current_assets::float / nullif(p.current_liabilities, 0))::int as f_delta_liquidity,
+            -- Share counts are the current ones in every fiscal year, so dilution is unknown until there are
+            -- share counts per year, which leaves the F-score incomplete
+            null::int as f_no_dilution,
+            (f.gross_operating_profit::float / nullif(f.revenue, 0) > p.gross_operating_profit::float / nullif(p.revenue, 0))::int as f_delta_margin,
+            (f.revenue::float / nullif(f.total_assets, 0) > p.revenue::float / nullif(p.total_assets, 0))::int as f_delta_turnover,
+
+            -- Altman ratios
+            (f.current_assets - f.current_liabilities)::float / nullif(f.total_assets, 0) as z_working_capital,
+            f.retained_earnings::float / nullif(f.total_assets, 0) as z_retained_earnings,
+            f.ebit::float / nullif(f.total_assets, 0) as z_ebit,
+            df.market_cap::float / nullif(f.total_liabilities, 0) as z_market_value,
+            f.revenue::float / nullif(f.total_assets, 0) as z_sales,
+
+            -- Beneish indices
+            (f.receivables::float / nullif(f.revenue, 0)) / nullif(p.receivables::float / nullif(p.revenue, 0), 0) as m_dsri,
+            (p.gross_operating_profit::float / nullif(p.revenue, 0)) / nullif(f.gross_operating_profit::float / nullif(f.revenue, 0), 0) as m_gmi,
+            (1 - (f.current_assets + f.ppe)::float / nullif(f.total_assets, 0)) / nullif(1 - (p.current_assets + p.ppe)::float / nullif(p.total_assets, 0), 0) as m_aqi,
+            f.revenue::float / nullif(p.revenue, 0) as m_sgi,
+            (p.depreciation::float / nullif(p.depreciation + p.ppe, 0)) / nullif(f.depreciation::float / nullif(f.depreciation + f.ppe, 0), 0) as m_depi,
+            (f.sga::float / nullif(f.revenue, 0)) / nullif(p.sga::float / nullif(p.revenue, 0), 0) as m_sgai,
+            (f.net_income - f.operating_cash_flow)::float / nullif(f.total_assets, 0) as m_tata,
+            ((f.current_liabilities + f.long_term_debt)::float / nullif(f.total_assets, 0)) / nullif((p.current_liabilities + p.long_term_debt)::float / nullif(p.total_assets, 0), 0) as m_lvgi
+        from financials f
+        left join financials p on p.company_id = f.company_id and p.fiscal_year = f.fiscal_year - 1
+        left join derived_financials df on df.company_id = f.company_id and df.fiscal_year = f.fiscal_year
+    )
+select
+    c.*,
+    c.f_roa + c.f_cfo + c.f_delta_roa + c.f_accruals + c.f_delta_leverage + c.f_delta_liquidity + c.f_no_dilution + c.f_delta_margin + c.f_delta_turnover as f_score,
+    1.2 * c.z_working_capital + 1.4 * c.z_retained_earnings + 3.3 * c.z_ebit + 0.6 * c.z_market_value + 1.0 * c.z_sales as z_score,
+    -4.84 + 0.92 * c.m_dsri + 0.528 * c.m_gmi + 0.404 * c.m_aqi + 0.892 * c.m_sgi + 0.115 * c.m_depi - 0.172 * c.m_sgai + 4.679 * c.m_tata - 0.327 * c.m_lvgi as m_score
+from components c;
+create unique index financial_scores_company_id_fiscal_year on financial_scores(company_id, fiscal_year);
+
+-- Valuations at the latest share price, against each company's most recent reported fiscal year.
+-- Has the same columns as derived_financials, along with the date of the price.
+create materialized view current_valuations as
+
+with
+    latest_financials as (
+        select distinct on (f.company_id) f.*
+        from financials f
+        order by f.company_id, f.fiscal_year desc
+    ),
+    latest_shares as (
+        select distinct on (s.company_id) s.company_id, s.date, s.close
+        from adjusted_shares s
+        order by s.company_id, s.date desc
+    )
+select
+    f.company_id,
+    f.fiscal_year,
+    s.date as price_date,
+    f.net_income::float * 10000 / nullif(f.number_of_shares, 0) as eps,
+    f.number_of_shares * s.close::float / 1000000 / nullif(f.net_income, 0) as pe,
+    (f.number_of_shares * s.close::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float) / nullif(f.ebit, 0) as evebit,
+    f.number_of_shares * s.close::float / 1000000 / nullif(f.revenue, 0) as ps,
+    f.number_of_shares * s.close::float / 1000000 / nullif((f.total_assets-f.total_liabilities), 0) as pb,
+    f.ebit::float / nullif(f.revenue, 0)::float as operating_margin,
+    f.ebit::float / nullif(f.net_income, 0)::float as net_margin,
+    f.net_income::float / nullif(f.equity, 0)::float as roe,
+    f.ebit::float / nullif(f.ppe + f.total_assets - f.total_liabilities, 0)::float as roc,
+    f.total_liabilities::float / nullif(f.equity, 0)::float as liabilities_to_equity,
+    (f.long_term_debt + f.current_debt - f.cash_and_equivalents)::float / nullif(f.ebit, 0)::float as debt_to_ebit,
+    (f.long_term_debt + f.current_debt)::float / nullif(f.total_assets, 0)::float as debt_to_assets,
+    f.operating_cash_flow::float / nullif(f.net_income, 0)::float as cash_conversion,
+    round(f.number_of_shares * s.close::float / 1000000)::bigint as market_cap,
+    round(f.number_of_shares * s.close::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float)::bigint as enterprise_value
+from latest_financials f
+inner join latest_shares s on s.company_id = f.company_id;
+create unique index current_valuations_company_id on current_valuations(company_id);
+
+create materialized view magic_formula_rankings as
+
+WITH
+	calcs AS (
+		SELECT
+			f.company_id,
+			f.fiscal_year,
+			f.ebit::numeric / (f.ppe + f.total_assets - f.total_liabilities) AS roc,
+			f.ebit / ((f.number_of_shares * s.average + f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::numeric / 1000000) AS yield
+		FROM
+			financials f
+			INNER JOIN adjusted_shares s ON f.company_id = s.company_id
+			AND s.date = TO_DATE((f.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD')
+		WHERE
+			s.average > 0
+	),
+	ranks AS (
+		SELECT
+			c.company_id,
+			c.fiscal_year,
+			c.roc,
+			c.yield,
+			rank() OVER (
+				PARTITION BY
+					c.fiscal_year
+				ORDER BY
+					c.roc DESC
+			) AS roc_rank,
+			rank() OVER (
+				PARTITION BY
+					c.fiscal_year
+				ORDER BY
+					c.yield DESC
+			) AS yield_rank
+		FROM
+			calcs c
+	)
+SELECT
+	r.company_id,
+	r.fiscal_year,
+	r.roc,
+	r.yield,
+	r.roc_rank,
+	r.yield_rank,
+	rank() OVER (
+		PARTITION BY
+			r.fiscal_year
+		ORDER BY
+			r.roc_rank + r.yield_rank ASC
+	) AS RANK
+FROM
+	ranks r;
+create unique index magic_formula_rankings_company_id_fiscal_year on magic_formula_rankings(company_id, fiscal_year);
+create index magic_formula_rankings_fiscal_year_rank on magic_formula_rankings(fiscal_year, rank);
+
+-- Dividends paid during each fiscal year, for companies with any dividends at all
+create materialized view dividend_metrics as
+
+with
+    annual as (
+        select
+            f.company_id,
+            f.fiscal_year,
+            f.net_income,
+            f.number_of_shares,
+            coalesce(sum(d.amount), 0) as dps,
+            coalesce(sum(d.reporting_amount), 0) as reporting_dps
+        from financials f
+        left join company_dividends d on d.company_id = f.company_id and extract(year from d.ex_date)::int = f.fiscal_year
+        where f.company_id in (select company_id from dividends)
+        group by f.company_id, f.fiscal_year, f.net_income, f.number_of_shares
+    ),
+    growth as (
+        select
+            a.*,
+            coalesce(a.dps > lag(a.dps) over w and lag(a.fiscal_year) over w = a.fiscal_year - 1, false) as grew
+        from annual a
+        window w as (partition by a.company_id order by a.fiscal_year)
+    ),
+    streaks as (
+        select
+            g.*,
+            count(*) filter (where not g.grew) over (partition by g.company_id order by g.fiscal_year) as streak
+        from growth g
+    )
+select
+    s.company_id,
+    s.fiscal_year,
+    s.dps / 100 as dividends_per_share,
+    s.dps / nullif(sh.average, 0) as dividend_yield,
+    s.reporting_dps * s.number_of_shares / 1000000 / nullif(s.net_income, 0) as payout_ratio,
+    row_number() over (partition by s.company_id, s.streak order by s.fiscal_year) - 1 as growth_years
+from streaks s
+left join adjusted_shares sh on sh.company_id = s.company_id and sh.date = to_date((s.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD');
+create unique index dividend_metrics_company_id_fiscal_year on dividend_metrics(company_id, fiscal_year);
+
+-- Closing prices with dividends reinvested on their ex-dates, indexed to the first close so that
+-- they're in the same precision as share prices
+create materialized view total_returns as
+
+with
+    prices as (
+        select
+            s.company_id,
+            s.date,
+            s.close,
+            lag(s.close) over w as previous_close,
+            lag(s.date) over w as previous_date
+        from adjusted_shares s
+        window w as (partition by s.company_id order by s.date)
+    ),
+    returns as (
+        select
+            p.*,
+            coalesce((
+                select sum(d.amount)
+                from company_dividends d
+                where d.company_id = p.company_id and d.ex_date > p.previous_date and d.ex_date <= p.date
+            ), 0) as dividend
+        from prices p
+    )
+select
+    r.company_id,
+    r.date,
+    r.close,
+    r.dividend,
+    first_value(r.close) over w * exp(sum(
+        case when r.previous_close > 0 and r.close + r.dividend > 0 then ln((r.close + r.dividend) / r.previous_close::float8) else 0 end
+    ) over w) as total_return
+from returns r
+window w as (partition by r.company_id order by r.date);
+create unique index total_returns_company_id_date on total_returns(company_id, date);
+
+-- Momentum, volatility and liquidity of each company's share price, as of the valuation date of each
+-- fiscal year (January 2 of the following year, as in derived_financials) and as of the latest price.
+-- Windows are in trading days, and a metric is null unless its whole window has prices.
+create materialized view price_metrics as
+
+with
+    anchors as (
+        select
+            s.company_id,
+            extract(year from s.date)::int - 1 as fiscal_year,
+            s.date,
+            s.close,
+            false as current
+        from adjusted_shares s
+        where to_char(s.date, 'MM-DD') = '01-02'
+
+        union all
+
+        select distinct on (s.company_id)
+            s.company_id,
+            extract(year from s.date)::int - 1 as fiscal_year,
+            s.date,
+            s.close,
+            true as current
+        from adjusted_shares s
+        order by s.company_id, s.date desc
+    )
+select
+    a.company_id,
+    a.fiscal_year,
+    a.date,
+    a.current,
+
+    a.close::float / nullif((select s.close from adjusted_shares s where s.company_id = a.company_id and s.date <= a.date - interval '1 month' order by s.date desc limit 1), 0) - 1 as return_1m,
+    a.close::float / nullif((select s.close from adjusted_shares s where s.company_id = a.company_id and s.date <= a.date - interval '3 months' order by s.date desc limit 1), 0) - 1 as return_3m,
+    a.close::float / nullif((select s.close from adjusted_shares s where s.company_id = a.company_id and s.date <= a.date - interval '6 months' order by s.date desc limit 1), 0) - 1 as return_6m,
+    a.close::float / nullif((select s.close from adjusted_shares s where s.company_id = a.company_id and s.date <= a.date - interval '12 months' order by s.date desc limit 1), 0) - 1 as return_12m,
+
+    a.close::float / nullif(y.high, 0) - 1 as high_52w_distance,
+    a.close::float / nullif(y.low, 0) - 1 as low_52w_distance,
+
+    a.close::float / nullif(ma50.average, 0) - 1 as ma50_position,
+    a.close::float / nullif(ma200.average, 0) - 1 as ma200_position,
+
+    rsi.rsi,
+    vol.volatility,
+
+    -- In the same precision and currency as the financials
+    tv.traded_value * tcr.rate as traded_value
+from anchors a
+
+left join lateral (
+    select
+        max(s.high) as high,
+        min(s.low) as low
+    from adjusted_shares s
+    where s.company_id = a.company_id and s.date > a.date - interval '1 year' and s.date <= a.date
+) y on true
+
+left join lateral (
+    select case when count(*) = 50 then avg(t.close) end as average
+    from (
+        select s.close
+        from adjusted_shares s
+        where s.company_id = a.company_id and s.date <= a.date
+        order by s.date desc
+        limit 50
+    ) t
+) ma50 on true
+
+left join lateral (
+    select case when count(*) = 200 then avg(t.close) end as average
+    from (
+        select s.close
+        from adjusted_shares s
+        where s.company_id = a.company_id and s.date <= a.date
+        order by s.date desc
+        limit 200
+    ) t
+) ma200 on true
+
+-- 14-day relative strength index, by simple averages of the gains and losses
+left join lateral (
+    select case when count(t.change) = 14 then 100 * sum(greatest(t.change, 0)) / nullif(sum(abs(t.change)), 0) end as rsi
+    from (
+        select t.close - lag(t.close) over (order by t.date) as change
+        from (
+            select s.date, s.close
+            from adjusted_shares s
+            where s.company_id = a.company_id and s.date <= a.date
+            order by s.date desc
+            limit 15
+        ) t
+    ) t
+) rsi on true
+
+-- Annualized standard deviation of the daily log returns over a year
+left join lateral (
+    select case when count(t.r) = 252 then stddev_samp(t.r) * sqrt(252) end as volatility
+    from (
+        select ln(t.close::float / nullif(lag(t.close) over (order by t.date), 0)) as r
+        from (
+            select s.date, s.close
+            from adjusted_shares s
+            where s.company_id = a.company_id and s.date <= a.date and s.close > 0
+            order by s.date desc
+            limit 253
+        ) t
+    ) t
+) vol on true
+
+-- Average daily traded value over three months
+left join lateral (
+    select avg(s.close::float * s.volume / 100000000) as traded_value
+    from adjusted_shares s
+    where s.company_id = a.company_id and s.date > a.date - interval '3 months' and s.date <= a.date
+) tv on true
+
+-- Rate from the trading currency to the reporting currency, of the latest fiscal year with rates as
+-- of the date, so that the traded value is comparable with the financials
+left join lateral (
+    select
+        case
+            when tc.currency_id = c."currencyId" then 1
+            else cr.average::float8 / nullif(tr.average, 0)
+        end as rate
+    from companies c
+    inner join trading_currencies tc on tc.company_id = c.id
+    left join annual_currency_rates tr on tr.currency_id = tc.currency_id and tr.fiscal_year <= extract(year from a.date)::int
+    left join annual_currency_rates cr on cr.currency_id = c."currencyId" and cr.fiscal_year = tr.fiscal_year
+    where c.id = a.company_id
+    order by tr.fiscal_year desc nulls last
+    limit 1
+) tcr on true;
+create unique index price_metrics_company_id_fiscal_year_current on price_metrics(company_id, fiscal_year, current);
\ No newline at end of file
@@ -0,0 +1,3 @@
+delete from view_refreshes where name = 'ttm_valuations';
+
+drop materialized view ttm_valuations;
\ No newline at end of file
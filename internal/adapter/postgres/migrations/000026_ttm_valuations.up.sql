@@ -0,0 +1,34 @@
+-- Valuations at the latest share price, against each company's trailing twelve months. Has the same
+-- columns as current_valuations, with the fiscal year of the latest quarter or half-year.
+create materialized view ttm_valuations as
+
+with
+    latest_shares as (
+        select distinct on (s.company_id) s.company_id, s.date, s.close
+        from adjusted_shares s
+        order by s.company_id, s.date desc
+    )
+select
+    f.company_id,
+    f.fiscal_year,
+    s.date as price_date,
+    f.net_income::float * 10000 / nullif(f.number_of_shares, 0) as eps,
+    f.number_of_shares * s.close::float / 1000000 / nullif(f.net_income, 0) as pe,
+    (f.number_of_shares * s.close::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float) / nullif(f.ebit, 0) as evebit,
+    f.number_of_shares * s.close::float / 1000000 / nullif(f.revenue, 0) as ps,
+    f.number_of_shares * s.close::float / 1000000 / nullif((f.total_assets-f.total_liabilities), 0) as pb,
+    f.ebit::float / nullif(f.revenue, 0)::float as operating_margin,
+    f.ebit::float / nullif(f.net_income, 0)::float as net_margin,
+    f.net_income::float / nullif(f.equity, 0)::float as roe,
+    f.ebit::float / nullif(f.ppe + f.total_assets - f.total_liabilities, 0)::float as roc,
+    f.total_liabilities::float / nullif(f.equity, 0)::float as liabilities_to_equity,
+    (f.long_term_debt + f.current_debt - f.cash_and_equivalents)::float / nullif(f.ebit, 0)::float as debt_to_ebit,
+    (f.long_term_debt + f.current_debt)::float / nullif(f.total_assets, 0)::float as debt_to_assets,
+    f.operating_cash_flow::float / nullif(f.net_income, 0)::float as cash_conversion,
+    round(f.number_of_shares * s.close::float / 1000000)::bigint as market_cap,
+    round(f.number_of_shares * s.close::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float)::bigint as enterprise_value
+from ttm_financials f
+inner join latest_shares s on s.company_id = f.company_id;
+create unique index ttm_valuations_company_id on ttm_valuations(company_id);
+
+insert into view_refreshes (name) values ('ttm_valuations');
\ No newline at end of file
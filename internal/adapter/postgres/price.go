@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+	"github.com/webmafia/pg"
+)
+
+type priceStore struct {
+	db
+}
+
+func NewPrice(pool *pg.DB) port.Price {
+	return priceStore{
+		db: db{pool},
+	}
+}
+
+// CreateShares implements port.Price
+func (s priceStore) CreateShares(ctx context.Context, shares []domain.Share) (err error) {
+	companyIds := make([]xid.ID, len(shares))
+	dates := make([]time.Time, len(shares))
+	opens := make([]float64, len(shares))
+	highs := make([]float64, len(shares))
+	lows := make([]float64, len(shares))
+	closes := make([]float64, len(shares))
+	volumes := make([]int, len(shares))
+	averages := make([]float64, len(shares))
+
+	for i, share := range shares {
+		companyIds[i] = share.CompanyID
+		dates[i] = share.Date
+		opens[i] = share.Open
+		highs[i] = share.High
+		lows[i] = share.Low
+		closes[i] = share.Close
+		volumes[i] = share.Volume
+		averages[i] = share.Average
+	}
+
+	// The whole batch at once, so that corrected bars replace the stored ones in a single round trip.
+	// A bar that occurs more than once is taken from its last occurrence, as a row can only be
+	// upserted once per statement.
+	_, err = s.db.Exec(ctx, `
+		insert into %T (company_id, date, open, high, low, close, volume, average)
+		select distinct on (s.company_id, s.date) s.company_id, s.date, s.open::int, s.high::int, s.low::int, s.close::int, s.volume, s.average::int
+		from unnest(%c::text[], %c::timestamp[], %c::float8[], %c::float8[], %c::float8[], %c::float8[], %c::bigint[], %c::float8[]) with ordinality s(company_id, date, open, high, low, close, volume, average, n)
+		order by s.company_id, s.date, s.n desc
+		on conflict (company_id, date) do update set
+			open = excluded.open,
+			high = excluded.high,
+			low = excluded.low,
+			close = excluded.close,
+			volume = excluded.volume,
+			average = excluded.average
+	`, Share, companyIds, dates, opens, highs, lows, closes, volumes, averages)
+
+	return
+}
+
+// IteratePriceIngestions implements port.Price
+func (s priceStore) IteratePriceIngestions(ctx context.Context, filters domain.PriceIngestionFilter) iter.Seq2[*domain.PriceIngestion, error] {
+	return func(yield func(*domain.PriceIngestion, error) bool) {
+		c := Company.Alias("c")
+		pi := PriceIngestions.Alias("pi")
+		cond := priceIngestionsFilter(filters, c, pi)
+
+		rows, err := s.db.Query(ctx, `
+			with
+				latest as (
+					select s.company_id, max(s.date) as last_date
+					from %T s
+					group by s.company_id
+				),
+				gaps as (
+					select g.company_id, min(g.previous) as gap_date
+					from (
+						select s.company_id, s.date, lag(s.date) over (partition by s.company_id order by s.date) as previous
+						from %T s
+						where s.date > now() - make_interval(days => %c)
+					) g
+					where g.date - g.previous > make_interval(days => %c)
+					group by g.company_id
+				)
+			select
+				c.id,
+				c."orderbookId",
+				pi.attempted,
+				pi.succeeded,
+				coalesce(pi.error, ''),
+				l.last_date,
+				g.gap_date
+			from %T
+			left join %T on pi.company_id = c.id
+			left join latest l on l.company_id = c.id
+			left join gaps g on g.company_id = c.id
+			where %c
+			order by c.id
+		`, Share, Share, domain.PriceBackfillDays, domain.PriceGapDays, c, pi, cond)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var ingestion domain.PriceIngestion
+
+			if err = rows.Scan(
+				&ingestion.CompanyID,
+				&ingestion.OrderbookID,
+				&ingestion.Attempted,
+				&ingestion.Succeeded,
+				&ingestion.Error,
+				&ingestion.LastDate,
+				&ingestion.GapDate,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&ingestion, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Only companies with an orderbook have prices to ingest.
+func priceIngestionsFilter(filters domain.PriceIngestionFilter, c pg.Alias, pi pg.Alias) pg.QueryEncoder {
+	cond := pg.And(pg.Raw(`%T <> ''`, c.Col("orderbookId")))
+
+	if filters.Failed {
+		cond.And(pg.Raw(`%T <> ''`, pi.Col("error")))
+	}
+
+	if !filters.NotSucceededSince.IsZero() {
+		cond.And(pg.Raw(`(%T is null or %T < %c)`, pi.Col("succeeded"), pi.Col("succeeded"), filters.NotSucceededSince))
+	}
+
+	return cond
+}
+
+// SetPriceIngestion implements port.Price
+func (s priceStore) SetPriceIngestion(ctx context.Context, ingestion *domain.PriceIngestion) (err error) {
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	vals.
+		Value("company_id", ingestion.CompanyID).
+		Value("attempted", ingestion.Attempted.Content).
+		Value("error", ingestion.Error)
+
+	// A failed attempt keeps the latest successful one
+	if ingestion.Succeeded.Valid {
+		vals.Value("succeeded", ingestion.Succeeded.Content)
+	}
+
+	_, err = s.db.InsertValues(ctx, PriceIngestions, vals, pg.InsertOptions{OnConflict: pg.DoUpdate(1, "company_id")})
+
+	return
+}
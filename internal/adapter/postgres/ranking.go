@@ -0,0 +1,401 @@
+package postgres
+
+import (
+	"context"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+	"github.com/webmafia/pg"
+)
+
+type rankingStore struct {
+	db
+}
+
+func NewRanking(pool *pg.DB) port.Ranking {
+	return rankingStore{
+		db: db{pool},
+	}
+}
+
+// CreateRanking implements port.Ranking
+func (s rankingStore) CreateRanking(ctx context.Context, ranking *domain.Ranking) (err error) {
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	ranking.Created = time.Now()
+	ranking.Updated = ranking.Created
+
+	vals.
+		Value("id", ranking.ID).
+		Value("name", ranking.Name).
+		Value("description", ranking.Description).
+		Value("factors", ranking.Factors).
+		Value("created", ranking.Created).
+		Value("updated", ranking.Updated)
+
+	_, err = s.db.InsertValues(ctx, Rankings, vals)
+
+	return
+}
+
+// ReadRanking implements port.Ranking
+func (s rankingStore) ReadRanking(ctx context.Context, ranking *domain.Ranking) (err error) {
+	r := Rankings.Alias("r")
+
+	row := s.db.QueryRow(ctx, `
+		select
+			r.name,
+			r.description,
+			r.factors,
+			r.created,
+			r.updated
+		from %T
+		where %c
+	`, r, pg.Eq(r.Col("id"), ranking.ID))
+
+	err = row.Scan(
+		&ranking.Name,
+		&ranking.Description,
+		&ranking.Factors,
+		&ranking.Created,
+		&ranking.Updated,
+	)
+
+	return
+}
+
+// UpdateRanking implements port.Ranking
+func (s rankingStore) UpdateRanking(ctx context.Context, ranking *domain.Ranking) (err error) {
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	ranking.Updated = time.Now()
+
+	vals.
+		Value("name", ranking.Name).
+		Value("description", ranking.Description).
+		Value("factors", ranking.Factors).
+		Value("updated", ranking.Updated)
+
+	_, err = s.db.UpdateValues(ctx, Rankings, vals, pg.Eq("id", ranking.ID))
+
+	return
+}
+
+// DeleteRanking implements port.Ranking
+func (s rankingStore) DeleteRanking(ctx context.Context, rankingId xid.ID) (err error) {
+	_, err = s.db.Delete(ctx, Rankings, pg.Eq("id", rankingId))
+	return
+}
+
+// CountRankings implements port.Ranking
+func (s rankingStore) CountRankings(ctx context.Context, filters domain.RankingFilter) (count int, err error) {
+	r := Rankings.Alias("r")
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, r, rankingsFilter(filters))
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateRankings implements port.Ranking
+func (s rankingStore) IterateRankings(ctx context.Context, filters domain.RankingFilter) iter.Seq2[*domain.Ranking, error] {
+	r := Rankings.Alias("r")
+
+	return s.iterateRankings(ctx, rankingsFilter(filters), pg.Order(r.Col(filters.OrderBy), filters.Order), filters.Offset, filters.Limit)
+}
+
+func (s rankingStore) iterateRankings(ctx context.Context, cond pg.QueryEncoder, orderBy pg.QueryEncoder, offset int, limit int) iter.Seq2[*domain.Ranking, error] {
+	return func(yield func(*domain.Ranking, error) bool) {
+		r := Rankings.Alias("r")
+
+		rows, err := s.db.Query(ctx, `
+			select
+				r.id,
+				r.name,
+				r.description,
+				r.factors,
+				r.created,
+				r.updated
+			from %T
+			where %c
+			order by %T
+			offset %d
+			limit %d
+		`, r, cond, orderBy, offset, limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var ranking domain.Ranking
+
+			if err = rows.Scan(
+				&ranking.ID,
+				&ranking.Name,
+				&ranking.Description,
+				&ranking.Factors,
+				&ranking.Created,
+				&ranking.Updated,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&ranking, nil) {
+				return
+			}
+		}
+	}
+}
+
+func rankingsFilter(filters domain.RankingFilter) pg.QueryEncoder {
+	cond := pg.And()
+
+	if filters.Search != "" {
+		cond.And(pg.Raw(`r.name ilike %c`, "%"+filters.Search+"%"))
+	}
+
+	return cond
+}
+
+// CountCompositeRanks implements port.Ranking
+func (s rankingStore) CountCompositeRanks(ctx context.Context, ranking domain.Ranking, filters domain.CompositeRankFilter) (count int, err error) {
+	q, err := rankingQuery(ranking, filters.FiscalYear)
+
+	if err != nil {
+		return
+	}
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from (%T) r
+		inner join %T c on c.id = r.company_id
+		where %c
+	`, q, Company, compositeRanksFilter(filters))
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateCompositeRanks implements port.Ranking
+func (s rankingStore) IterateCompositeRanks(ctx context.Context, ranking domain.Ranking, filters domain.CompositeRankFilter) iter.Seq2[*domain.CompositeRank, error] {
+	return func(yield func(*domain.CompositeRank, error) bool) {
+		q, err := rankingQuery(ranking, filters.FiscalYear)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		cols := make([]pg.StringEncoder, 0, 2*len(ranking.Factors))
+
+		for i := range ranking.Factors {
+			n := strconv.Itoa(i)
+			cols = append(cols, pg.Col("r.v"+n), pg.Col("r.r"+n))
+		}
+
+		rows, err := s.db.Query(ctx, `
+			select
+				c.id,
+				c.name,
+				%T,
+				r.score,
+				r.rank
+			from (%T) r
+			inner join %T c on c.id = r.company_id
+			where %c
+			order by %T
+			offset %d
+			limit %d
+		`, pg.Columns(cols), q, Company, compositeRanksFilter(filters), pg.Order(pg.Col("r.rank"), filters.Order), filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			rank := domain.CompositeRank{
+				FiscalYear: filters.FiscalYear,
+				Factors:    make([]domain.FactorRank, len(ranking.Factors)),
+			}
+
+			scans := make([]any, 0, len(cols)+4)
+			scans = append(scans, &rank.Company.ID, &rank.Company.Name)
+
+			for i, f := range ranking.Factors {
+				rank.Factors[i].Metric = f.Metric
+				scans = append(scans, &rank.Factors[i].Value, &rank.Factors[i].Rank)
+			}
+
+			scans = append(scans, &rank.Score, &rank.Rank)
+
+			if err = rows.Scan(scans...); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&rank, nil) {
+				return
+			}
+		}
+	}
+}
+
+func compositeRanksFilter(filters domain.CompositeRankFilter) pg.QueryEncoder {
+	c := Company.Alias("c")
+	cond := pg.And()
+
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(c.Col("id"), filters.Include))
+	}
+
+	if filters.Search != "" {
+		cond.And(pg.Search(c.Col("ts"), filters.Search, pg.SearchOptions{
+			Preprocessor: pg.PrefixSearch,
+		}))
+	}
+
+	return cond
+}
+
+// Ranks all companies by each factor of a ranking for the fiscal year, and then by the weighted
+// percentiles of the factor ranks. The result has a value (vN) and rank (rN) column per factor,
+// along with the score and the final rank. Companies without a value for every factor are left out.
+func rankingQuery(ranking domain.Ranking, fiscalYear int) (pg.QueryEncoder, error) {
+	c := Company.Alias("c")
+	filters := domain.ScreenerFilter{FiscalYear: fiscalYear, SectorScope: domain.SectorScopeAll}
+	tables := map[domain.MetricSource]struct{}{
+		domain.MetricSourceCompanies: {},
+	}
+
+	joins := []pg.QueryEncoder{
+		currencyRates(domain.FXPolicyStandard, pg.Raw("%c", fiscalYear), c.Col("currencyId")),
+	}
+
+	values := make([]any, 0, len(ranking.Factors)+2)
+	weights := make([]any, 0, len(ranking.Factors)+1)
+	var selects, present, ranks, scores []string
+	var total float64
+
+	for i, f := range ranking.Factors {
+		metric, ok := domain.LookupMetric(f.Metric)
+
+		if !ok || !metric.Filterable || metric.Ranking != nil {
+			return nil, domain.ErrUnknownMetric.Detailed("cannot rank by \""+string(f.Metric)+"\"", "factors")
+		}
+
+		if join := screenerJoin(tables, metric, c, filters, nil); join != nil {
+			joins = append(joins, join)
+		}
+
+		n := strconv.Itoa(i)
+		v := "s.v" + n
+		best, worst := "desc", "asc"
+
+		if f.Direction == domain.RankingDirectionAsc {
+			best, worst = worst, best
+		}
+
+		partition := ""
+
+		if f.SectorNeutral {
+			partition = "partition by s.sector_id "
+		}
+
+		values = append(values, screenerValue(metric))
+		weights = append(weights, f.Weight)
+		total += f.Weight
+		selects = append(selects, "%T::float8 as v"+n)
+		present = append(present, v+" is not null")
+		ranks = append(ranks, v+", rank() over ("+partition+"order by "+v+" "+best+") as r"+n)
+		scores = append(scores, "%c::float8 * cume_dist() over ("+partition+"order by "+v+" "+worst+")")
+	}
+
+	values = append(values, c, pg.Multi(joins))
+	inner := pg.Raw(`select c.id as company_id, c."sectorId" as sector_id, `+strings.Join(selects, ", ")+` from %T %T`, values...)
+
+	weights = append(weights, total, inner)
+	scored := pg.Raw(`select s.company_id, `+strings.Join(ranks, ", ")+`, (`+strings.Join(scores, " + ")+`) / %c::float8 as score
+		from (%T) s
+		where `+strings.Join(present, " and "), weights...)
+
+	return pg.Raw(`select r.*, rank() over (order by r.score desc) as rank from (%T) r`, scored), nil
+}
+
+// Reads the rankings that the screener filter refers to, and returns their ranking queries by
+// metric.
+func (s rankingStore) screenerRankings(ctx context.Context, filters domain.ScreenerFilter, expr domain.FilterExpr) (rankings map[domain.MetricID]pg.QueryEncoder, err error) {
+	metrics := make(map[xid.ID]domain.MetricID)
+	add := func(id domain.MetricID) {
+		if metric, ok := domain.LookupMetric(id); ok && metric.Ranking != nil {
+			metrics[*metric.Ranking] = id
+		}
+	}
+
+	for _, id := range filters.Columns {
+		add(id)
+	}
+
+	add(filters.OrderBy)
+
+	for metric := range filters.RangedMetrics() {
+		add(metric.ID)
+	}
+
+	domain.WalkMetrics(expr, func(name string) {
+		add(domain.MetricID(name))
+	})
+
+	if len(metrics) == 0 {
+		return
+	}
+
+	ids := make([]xid.ID, 0, len(metrics))
+
+	for id := range metrics {
+		ids = append(ids, id)
+	}
+
+	rankings = make(map[domain.MetricID]pg.QueryEncoder, len(metrics))
+	r := Rankings.Alias("r")
+
+	for ranking, err := range s.iterateRankings(ctx, pg.In(r.Col("id"), ids), pg.Order(r.Col("id"), "asc"), 0, len(ids)) {
+		if err != nil {
+			return nil, err
+		}
+
+		if rankings[ranking.MetricID()], err = rankingQuery(*ranking, filters.FiscalYear); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range metrics {
+		if _, ok := rankings[id]; !ok {
+			return nil, domain.ErrUnknownMetric.Detailed("unknown ranking \""+string(id)+"\"", "columns")
+		}
+	}
+
+	return
+}
@@ -0,0 +1,447 @@
+package postgres
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+	"github.com/webmafia/pg"
+)
+
+type savedScreenStore struct {
+	db
+}
+
+func NewSavedScreen(pool *pg.DB) port.SavedScreen {
+	return savedScreenStore{
+		db: db{pool},
+	}
+}
+
+// CreateSavedScreen implements port.SavedScreen
+func (s savedScreenStore) CreateSavedScreen(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	screen.Created = time.Now()
+	screen.Updated = screen.Created
+
+	vals.
+		Value("id", screen.ID).
+		Value("name", screen.Name).
+		Value("description", screen.Description).
+		Value("owner", screen.Owner).
+		Value("visibility", screen.Visibility).
+		Value("filter", screen.Filter).
+		Value("tracked", screen.Tracked).
+		Value("track_limit", screen.TrackLimit).
+		Value("created", screen.Created).
+		Value("updated", screen.Updated)
+
+	_, err = s.db.InsertValues(ctx, SavedScreens, vals)
+
+	return
+}
+
+// ReadSavedScreen implements port.SavedScreen
+func (s savedScreenStore) ReadSavedScreen(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	ss := SavedScreens.Alias("ss")
+
+	row := s.db.QueryRow(ctx, `
+		select
+			ss.id,
+			ss.name,
+			ss.description,
+			ss.owner,
+			ss.visibility,
+			ss.filter,
+			ss.tracked,
+			ss.track_limit,
+			ss.created,
+			ss.updated
+		from %T
+		where %c
+	`, ss, pg.Eq(ss.Col("id"), screen.ID))
+
+	err = row.Scan(
+		&screen.ID,
+		&screen.Name,
+		&screen.Description,
+		&screen.Owner,
+		&screen.Visibility,
+		&screen.Filter,
+		&screen.Tracked,
+		&screen.TrackLimit,
+		&screen.Created,
+		&screen.Updated,
+	)
+
+	return
+}
+
+// UpdateSavedScreen implements port.SavedScreen
+func (s savedScreenStore) UpdateSavedScreen(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	screen.Updated = time.Now()
+
+	vals.
+		Value("name", screen.Name).
+		Value("description", screen.Description).
+		Value("owner", screen.Owner).
+		Value("visibility", screen.Visibility).
+		Value("filter", screen.Filter).
+		Value("tracked", screen.Tracked).
+		Value("track_limit", screen.TrackLimit).
+		Value("updated", screen.Updated)
+
+	_, err = s.db.UpdateValues(ctx, SavedScreens, vals, pg.Eq("id", screen.ID))
+
+	return
+}
+
+// DeleteSavedScreen implements port.SavedScreen
+func (s savedScreenStore) DeleteSavedScreen(ctx context.Context, screenId xid.ID) (err error) {
+	_, err = s.db.Delete(ctx, SavedScreens, pg.Eq("id", screenId))
+	return
+}
+
+// CountSavedScreens implements port.SavedScreen
+func (s savedScreenStore) CountSavedScreens(ctx context.Context, filters domain.SavedScreenFilter) (count int, err error) {
+	ss := SavedScreens.Alias("ss")
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, ss, savedScreensFilter(filters, ss))
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateSavedScreens implements port.SavedScreen
+func (s savedScreenStore) IterateSavedScreens(ctx context.Context, filters domain.SavedScreenFilter) iter.Seq2[*domain.SavedScreen, error] {
+	return func(yield func(*domain.SavedScreen, error) bool) {
+		ss := SavedScreens.Alias("ss")
+
+		rows, err := s.db.Query(ctx, `
+			select
+				ss.id,
+				ss.name,
+				ss.description,
+				ss.owner,
+				ss.visibility,
+				ss.filter,
+				ss.tracked,
+				ss.track_limit,
+				ss.created,
+				ss.updated
+			from %T
+			where %c
+			order by %T
+			offset %d
+			limit %d
+		`, ss, savedScreensFilter(filters, ss), pg.Order(ss.Col(filters.OrderBy), filters.Order), filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var screen domain.SavedScreen
+
+			if err = rows.Scan(
+				&screen.ID,
+				&screen.Name,
+				&screen.Description,
+				&screen.Owner,
+				&screen.Visibility,
+				&screen.Filter,
+				&screen.Tracked,
+				&screen.TrackLimit,
+				&screen.Created,
+				&screen.Updated,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&screen, nil) {
+				return
+			}
+		}
+	}
+}
+
+func savedScreensFilter(filters domain.SavedScreenFilter, ss pg.Alias) pg.QueryEncoder {
+	cond := pg.And()
+	visible := pg.Or(pg.Eq(ss.Col("visibility"), domain.ScreenVisibilityPublic))
+
+	if filters.Owner != "" {
+		visible.Or(pg.Eq(ss.Col("owner"), filters.Owner))
+	}
+
+	if filters.Tracked {
+		cond.And(pg.Eq(ss.Col("tracked"), true))
+	} else {
+		cond.And(visible)
+	}
+
+	if filters.Search != "" {
+		cond.And(pg.Raw(`ss.name ilike %c`, "%"+filters.Search+"%"))
+	}
+
+	return cond
+}
+
+// CreateScreenSnapshot implements port.SavedScreen
+func (s savedScreenStore) CreateScreenSnapshot(ctx context.Context, snapshot *domain.ScreenSnapshot) (err error) {
+	if ctx, err = s.AcquireContext(ctx); err != nil {
+		return
+	}
+	defer s.ReleaseContext(ctx)
+
+	snapshot.Created = time.Now()
+	snapshot.Count = len(snapshot.Members)
+
+	vals := s.db.AcquireValues()
+	defer s.db.ReleaseValues(vals)
+
+	vals.
+		Value("id", snapshot.ID).
+		Value("screen_id", snapshot.ScreenID).
+		Value("fiscal_year", snapshot.FiscalYear).
+		Value("created", snapshot.Created)
+
+	if _, err = s.db.InsertValues(ctx, ScreenSnapshots, vals); err != nil {
+		return
+	}
+
+	companyIds := make([]xid.ID, len(snapshot.Members))
+	ranks := make([]int, len(snapshot.Members))
+
+	for i, member := range snapshot.Members {
+		companyIds[i] = member.CompanyID
+		ranks[i] = member.Rank
+	}
+
+	// All members at once, as a screen can have up to a thousand
+	if _, err = s.db.Exec(ctx, `
+		insert into %T (snapshot_id, company_id, rank)
+		select %c, m.company_id, m.rank
+		from unnest(%c::text[], %c::int[]) m(company_id, rank)
+	`, ScreenSnapshotMembers, snapshot.ID, companyIds, ranks); err != nil {
+		return
+	}
+
+	return s.CommitContext(ctx)
+}
+
+// CountScreenSnapshots implements port.SavedScreen
+func (s savedScreenStore) CountScreenSnapshots(ctx context.Context, filters domain.ScreenSnapshotFilter) (count int, err error) {
+	sn := ScreenSnapshots.Alias("sn")
+
+	row := s.db.QueryRow(ctx, `
+		select
+			count(*)
+		from %T
+		where %c
+	`, sn, pg.Eq(sn.Col("screen_id"), filters.ScreenID))
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateScreenSnapshots implements port.SavedScreen
+func (s savedScreenStore) IterateScreenSnapshots(ctx context.Context, filters domain.ScreenSnapshotFilter) iter.Seq2[*domain.ScreenSnapshot, error] {
+	return func(yield func(*domain.ScreenSnapshot, error) bool) {
+		sn := ScreenSnapshots.Alias("sn")
+
+		rows, err := s.db.Query(ctx, `
+			select
+				sn.id,
+				sn.screen_id,
+				sn.fiscal_year,
+				(select count(*) from %T m where m.snapshot_id = sn.id),
+				sn.created
+			from %T
+			where %c
+			order by sn.created desc
+			offset %d
+			limit %d
+		`, ScreenSnapshotMembers, sn, pg.Eq(sn.Col("screen_id"), filters.ScreenID), filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var snapshot domain.ScreenSnapshot
+
+			if err = rows.Scan(
+				&snapshot.ID,
+				&snapshot.ScreenID,
+				&snapshot.FiscalYear,
+				&snapshot.Count,
+				&snapshot.Created,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&snapshot, nil) {
+				return
+			}
+		}
+	}
+}
+
+// IterateScreenChanges implements port.SavedScreen
+func (s savedScreenStore) IterateScreenChanges(ctx context.Context, screenId xid.ID, from xid.ID, to xid.ID) iter.Seq2[*domain.ScreenMemberChange, error] {
+	return func(yield func(*domain.ScreenMemberChange, error) bool) {
+		sn := ScreenSnapshots.Alias("sn")
+
+		for location, id := range [...]xid.ID{from, to} {
+			var count int
+
+			row := s.db.QueryRow(ctx, `
+				select
+					count(*)
+				from %T
+				where %c
+			`, sn, pg.And(pg.Eq(sn.Col("id"), id), pg.Eq(sn.Col("screen_id"), screenId)))
+
+			if err := row.Scan(&count); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if count == 0 {
+				yield(nil, domain.ErrUnknownSnapshot.Detailed("snapshot "+id.String()+" isn't of the screen", [...]string{"from", "to"}[location]))
+				return
+			}
+		}
+
+		// Both snapshots are also restricted to the screen in the comparison itself, so that one
+		// screen's snapshots are never compared with another's
+		rows, err := s.db.Query(ctx, `
+			with
+				a as (
+					select m.company_id, m.rank
+					from %T m
+					inner join %T sn on sn.id = m.snapshot_id
+					where m.snapshot_id = %c and sn.screen_id = %c
+				),
+				b as (
+					select m.company_id, m.rank
+					from %T m
+					inner join %T sn on sn.id = m.snapshot_id
+					where m.snapshot_id = %c and sn.screen_id = %c
+				)
+			select
+				c.id,
+				c.name,
+				a.rank,
+				b.rank
+			from a
+			full join b on b.company_id = a.company_id
+			inner join %T c on c.id = coalesce(a.company_id, b.company_id)
+			where a.rank is distinct from b.rank
+			order by b.rank nulls last, a.rank
+		`, ScreenSnapshotMembers, ScreenSnapshots, from, screenId, ScreenSnapshotMembers, ScreenSnapshots, to, screenId, Company)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var change domain.ScreenMemberChange
+
+			if err = rows.Scan(
+				&change.Company.ID,
+				&change.Company.Name,
+				&change.FromRank,
+				&change.ToRank,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			switch {
+			case !change.FromRank.Valid:
+				change.Change = domain.ScreenChangeAdded
+			case !change.ToRank.Valid:
+				change.Change = domain.ScreenChangeRemoved
+			default:
+				change.Change = domain.ScreenChangeMoved
+			}
+
+			if !yield(&change, nil) {
+				return
+			}
+		}
+	}
+}
+
+// IterateScreenMembership implements port.SavedScreen
+func (s savedScreenStore) IterateScreenMembership(ctx context.Context, screenId xid.ID, companyId xid.ID) iter.Seq2[*domain.ScreenMembership, error] {
+	return func(yield func(*domain.ScreenMembership, error) bool) {
+		sn := ScreenSnapshots.Alias("sn")
+
+		rows, err := s.db.Query(ctx, `
+			select
+				sn.id,
+				sn.fiscal_year,
+				sn.created,
+				m.rank
+			from %T
+			left join %T m on m.snapshot_id = sn.id and %c
+			where %c
+			order by sn.created
+		`, sn, ScreenSnapshotMembers, pg.Eq(pg.Col("m.company_id"), companyId), pg.Eq(sn.Col("screen_id"), screenId))
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var membership domain.ScreenMembership
+
+			if err = rows.Scan(
+				&membership.SnapshotID,
+				&membership.FiscalYear,
+				&membership.Created,
+				&membership.Rank,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			membership.Member = membership.Rank.Valid
+
+			if !yield(&membership, nil) {
+				return
+			}
+		}
+	}
+}
@@ -3,9 +3,11 @@ package postgres
 import (
 	"context"
 	"iter"
+	"strconv"
 
 	"github.com/dagulv/screener/internal/core/domain"
 	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
 	"github.com/webmafia/pg"
 )
 
@@ -28,8 +30,29 @@ func NewScreener(pool *pg.DB) port.Screener {
 func (s screenerStore) CountScreener(ctx context.Context, filters domain.ScreenerFilter) (count int, err error) {
 	c := Company.Alias("c")
 
-	_, joins := screenerQuery(filters, c)
-	cond := screenerFilter(filters)
+	expr, err := domain.ParseFilterExpression(filters.Expression)
+
+	if err != nil {
+		return
+	}
+
+	rankings, err := rankingStore{s.db}.screenerRankings(ctx, filters, expr)
+
+	if err != nil {
+		return
+	}
+
+	_, joins, err := screenerQuery(filters, expr, c, rankings)
+
+	if err != nil {
+		return
+	}
+
+	cond, err := screenerFilter(filters, expr)
+
+	if err != nil {
+		return
+	}
 
 	row := s.db.QueryRow(ctx, `
 			select
@@ -48,24 +71,67 @@ func (s screenerStore) CountScreener(ctx context.Context, filters domain.Screene
 func (s screenerStore) IterateScreener(ctx context.Context, filters domain.ScreenerFilter) iter.Seq2[*domain.Screener, error] {
 	return func(yield func(*domain.Screener, error) bool) {
 		c := Company.Alias("c")
-		curr := Currency.Alias("curr")
-		// cr := QuarterlyCurrencyRates.Alias("cr")
 
-		cols, joins := screenerQuery(filters, c)
-		cond := screenerFilter(filters)
-		orderBy := screenerOrderBy(filters.OrderBy)
+		expr, err := domain.ParseFilterExpression(filters.Expression)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		rankings, err := rankingStore{s.db}.screenerRankings(ctx, filters, expr)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		cols, joins, err := screenerQuery(filters, expr, c, rankings)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		cond, err := screenerFilter(filters, expr)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		orderBy, err := screenerOrderBy(filters.OrderBy)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		offset := filters.Offset
+
+		if filters.Cursor != "" {
+			cursor, err := domain.ParseScreenerCursor(filters.Cursor)
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			cond = pg.And(cond, screenerKeyset(orderBy, filters.Order, cursor))
+			offset = 0
+		}
 
 		rows, err := s.db.Query(ctx, `
 			select
+				%T,
 				%T
 			from %T
-			left join %T on curr.id = c."currencyId"
 			%T
 			where %c
-			order by %T nulls last
+			order by %T nulls last, c.id
 			offset %d
 			limit %d
-		`, cols, c, curr, pg.Multi(joins), cond, pg.Order(orderBy, filters.Order), filters.Offset, filters.Limit)
+		`, cols, orderBy, c, pg.Multi(joins), cond, pg.Order(orderBy, filters.Order), offset, filters.Limit)
 
 		if err != nil {
 			yield(nil, err)
@@ -76,15 +142,27 @@ func (s screenerStore) IterateScreener(ctx context.Context, filters domain.Scree
 
 		for rows.Next() {
 			var screener domain.Screener
+			var key any
 
 			if err = rows.Scan(
-				screenerScanColumns(&screener, filters.Columns)...,
+				append(screenerScanColumns(&screener, filters.Columns), &key)...,
 			); err != nil {
 				yield(nil, err)
 				return
 			}
 
-			screenerTransform(&screener)
+			screener.Cursor = screenerCursor(key, screener.CompanyId)
+
+			// Values are left in the reporting currency when there are no rates to convert them with
+			if screener.ExchangeRate.Valid && screener.BalanceExchangeRate.Valid {
+				screener.DisplayCurrency = filters.Currency
+			} else {
+				screener.ExchangeRate.Valid = false
+				screener.BalanceExchangeRate.Valid = false
+			}
+
+			screener.StalePrice = screener.PriceDate.Valid && filters.IsStale(screener.PriceDate.Content)
+			screenerTransform(&screener, filters.Columns)
 
 			if !yield(&screener, nil) {
 				return
@@ -93,391 +171,422 @@ func (s screenerStore) IterateScreener(ctx context.Context, filters domain.Scree
 	}
 }
 
-func screenerTransform(screener *domain.Screener) {
-	screener.Revenue.Content *= TransformConstant
-	screener.CostOfRevenue.Content *= TransformConstant
-	screener.GrossOperatingProfit.Content *= TransformConstant
-	screener.EBIT.Content *= TransformConstant
-	screener.NetIncome.Content *= TransformConstant
-	screener.TotalAssets.Content *= TransformConstant
-	screener.TotalLiabilities.Content *= TransformConstant
-	screener.CashAndEquivalents.Content *= TransformConstant
-	screener.ShortTermInvestments.Content *= TransformConstant
-	screener.LongTermDebt.Content *= TransformConstant
-	screener.CurrentDebt.Content *= TransformConstant
-	screener.Equity.Content *= TransformConstant
-	screener.OperatingCashFlow.Content *= TransformConstant
-	screener.CapitalExpenditures.Content *= TransformConstant
-	screener.FreeCashFlow.Content *= TransformConstant
-	screener.PPE.Content *= TransformConstant
+// Converts monetary values from the stored precision to whole currency units.
+func screenerTransform(screener *domain.Screener, cols []domain.MetricID) {
+	for _, id := range cols {
+		if metric, ok := domain.LookupMetric(id); ok && metric.Unit == domain.MetricUnitMoney {
+			switch v := metric.Target(screener).(type) {
+			case *domain.Nullable[int64]:
+				v.Content *= TransformConstant
+			case *domain.Nullable[float64]:
+				v.Content *= TransformConstant
+			}
+		}
+	}
+}
+
+// The value that a metric is filtered and sorted by. Monetary values are converted to millions
+// of the base currency, so that they are comparable between companies.
+func screenerValue(metric domain.Metric) pg.StringEncoder {
+	return pg.Col(screenerValueExpr(metric))
+}
+
+func screenerValueExpr(metric domain.Metric) string {
+	if metric.Currency {
+		return "(" + metric.Expr + "::float8 / " + strconv.Itoa(FloatConstant) + " / " + currencyRate(metric, "cr") + ")"
+	}
+
+	return "(" + metric.Expr + ")"
+}
+
+// The cursor of a row by its order key. Real keys are widened to the exact float8 that they're
+// compared as, rather than encoded at float4 precision, so that ties are neither repeated nor
+// skipped on the next page.
+func screenerCursor(key any, id xid.ID) string {
+	if v, ok := key.(float32); ok {
+		key = float64(v)
+	}
+
+	return domain.ScreenerCursor{Value: key, ID: id}.String()
+}
+
+func screenerOrderBy(orderBy domain.MetricID) (pg.StringEncoder, error) {
+	metric, ok := domain.LookupMetric(orderBy)
+
+	if !ok || !metric.Sortable {
+		return nil, domain.ErrUnknownMetric.Detailed("cannot order by \""+string(orderBy)+"\"", "orderby")
+	}
+
+	return screenerValue(metric), nil
 }
 
-func screenerOrderBy(orderBy string) any {
-	m := MagicFormulaRankings.Alias("m")
-	f := Financials.Alias("f")
+// Selects the rows after a cursor, in the same order as the screener: by the ordered value with
+// nulls last, and then by company.
+func screenerKeyset(orderBy pg.StringEncoder, order string, cursor domain.ScreenerCursor) pg.QueryEncoder {
 	c := Company.Alias("c")
-	df := DerivedFinancials.Alias("df")
-	sec := Sector.Alias("sec")
-	curr := Currency.Alias("curr")
 
-	switch orderBy {
-	case domain.ScreenerColumnName:
-		return c.Col("name")
-	case domain.ScreenerColumnMagicRank:
-		return m.Col("rank")
-	case domain.ScreenerColumnSector:
-		return sec.Col("name")
-	case "currency":
-		return curr.Col("name")
-	// Static financials with currency
-	case "revenue", "cost_of_revenue", "gross_operating_profit", "ebit", "net_income", "total_assets", "total_liabilities", "cash_and_equivalents", "short_term_investments", "long_term_debt", "current_debt", "equity", "operating_cash_flow", "capital_expenditures", "free_cash_flow", "ppe":
-		return pg.Col("f." + orderBy + "::real / cr.rate")
-	// Static financials without currency
-	case "number_of_shares":
-		return f.Col(orderBy)
-	// Derived financials
-	case "eps", "pe", "evebit", "ps", "pb", "operating_margin", "net_margin", "roe", "roc", "liabilities_to_equity", "debt_to_ebit", "debt_to_assets", "cash_conversion":
-		return df.Col(orderBy)
-	default:
-		return orderBy
+	if cursor.Value == nil {
+		return pg.Raw("%T is null and %T > %c", orderBy, c.Col("id"), cursor.ID)
+	}
+
+	op := ">"
+
+	if order == "desc" {
+		op = "<"
 	}
+
+	return pg.Or(
+		pg.Raw("%T "+op+" %c", orderBy, cursor.Value),
+		pg.Raw("%T = %c and %T > %c", orderBy, cursor.Value, c.Col("id"), cursor.ID),
+		pg.Raw("%T is null", orderBy),
+	)
 }
 
-func screenerFilter(filters domain.ScreenerFilter) pg.QueryEncoder {
+func screenerFilter(filters domain.ScreenerFilter, expr domain.FilterExpr) (pg.QueryEncoder, error) {
 	c := Company.Alias("c")
-	// cr := QuarterlyCurrencyRates.Alias("cr")
 	cond := pg.And()
+
+	screenerUniverse(cond, filters, c)
+
+	// The expression is applied along with a search, unlike the ranges that are meant for browsing
+	if expr != nil {
+		exprCond, err := screenerExpression(expr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cond.And(exprCond)
+	}
+
 	if filters.Search != "" {
 		cond.And(pg.Search(c.Col("ts"), filters.Search, pg.SearchOptions{
 			Preprocessor: pg.PrefixSearch,
 		}))
-		return cond
+		return cond, nil
 	}
-	m := MagicFormulaRankings.Alias("m")
-	// f := Financials.Alias("f")
-	// sec := Sector.Alias("sec")
-	df := DerivedFinancials.Alias("df")
 
-	if filters.CapitalExpenditures.Min.Valid {
-		cond.And(pg.Raw("(f.capital_expenditures::real / cr.rate) >= %c", filters.CapitalExpenditures.Min.Content*FloatConstant))
-	}
-	if filters.CapitalExpenditures.Max.Valid {
-		cond.And(pg.Raw("(f.capital_expenditures::real / cr.rate) <= %c", filters.CapitalExpenditures.Max.Content*FloatConstant))
+	for metric, r := range filters.RangedMetrics() {
+		if r.Min.Valid {
+			cond.And(pg.Raw("%T >= %c::float8", screenerValue(metric), r.Min.Content))
+		}
+		if r.Max.Valid {
+			cond.And(pg.Raw("%T <= %c::float8", screenerValue(metric), r.Max.Content))
+		}
 	}
 
-	if filters.EBIT.Min.Valid {
-		cond.And(pg.Raw("(f.ebit::real / cr.rate) >= %c", filters.EBIT.Min.Content*FloatConstant))
-	}
-	if filters.EBIT.Max.Valid {
-		cond.And(pg.Raw("(f.ebit::real / cr.rate) <= %c", filters.EBIT.Max.Content*FloatConstant))
-	}
+	return cond, nil
+}
 
-	if filters.Equity.Min.Valid {
-		cond.And(pg.Raw("(f.equity::real / cr.rate) >= %c", filters.Equity.Min.Content*FloatConstant))
-	}
-	if filters.Equity.Max.Valid {
-		cond.And(pg.Raw("(f.equity::real / cr.rate) <= %c", filters.Equity.Max.Content*FloatConstant))
-	}
+// Restricts the companies by country, marketplace, sector and currency.
+func screenerUniverse(cond pg.MultiAnd, filters domain.ScreenerFilter, c pg.Alias) {
+	screenerInclude(cond, c.Col("country_code"), filters.Countries, filters.ExcludeCountries)
+	screenerInclude(cond, c.Col("market_place_code"), filters.MarketPlaces, filters.ExcludeMarketPlaces)
+	screenerInclude(cond, c.Col("sectorId"), filters.Sectors, filters.ExcludeSectors)
+	screenerInclude(cond, c.Col("currencyId"), filters.Currencies, filters.ExcludeCurrencies)
+}
 
-	if filters.GrossOperatingProfit.Min.Valid {
-		cond.And(pg.Raw("(f.gross_operating_profit::real / cr.rate) >= %c", filters.GrossOperatingProfit.Min.Content*FloatConstant))
-	}
-	if filters.GrossOperatingProfit.Max.Valid {
-		cond.And(pg.Raw("(f.gross_operating_profit::real / cr.rate) <= %c", filters.GrossOperatingProfit.Max.Content*FloatConstant))
+func screenerInclude[T any](cond pg.MultiAnd, col pg.ChainedIdentifier, include []T, exclude []T) {
+	if len(include) > 0 {
+		cond.And(pg.In(col, include))
 	}
 
-	if filters.NetIncome.Min.Valid {
-		cond.And(pg.Raw("(f.net_income::real / cr.rate) >= %c", filters.NetIncome.Min.Content*FloatConstant))
-	}
-	if filters.NetIncome.Max.Valid {
-		cond.And(pg.Raw("(f.net_income::real / cr.rate) <= %c", filters.NetIncome.Max.Content*FloatConstant))
+	if len(exclude) > 0 {
+		cond.And(pg.Raw("%T <> all(%c)", col, exclude))
 	}
+}
 
-	if filters.OperatingCashFlow.Min.Valid {
-		cond.And(pg.Raw("(f.operating_cash_flow::real / cr.rate) >= %c", filters.OperatingCashFlow.Min.Content*FloatConstant))
-	}
-	if filters.OperatingCashFlow.Max.Valid {
-		cond.And(pg.Raw("(f.operating_cash_flow::real / cr.rate) <= %c", filters.OperatingCashFlow.Max.Content*FloatConstant))
-	}
+// Selects the columns of a screener filter, and joins the sources of all metrics it refers to. The
+// ranking queries of any composite ranks must be resolved beforehand.
+func screenerQuery(filters domain.ScreenerFilter, expr domain.FilterExpr, a pg.Alias, rankings map[domain.MetricID]pg.QueryEncoder) (pg.QueryEncoder, []pg.QueryEncoder, error) {
+	curr := Currency.Alias("curr")
 
-	if filters.Revenue.Min.Valid {
-		// cond.And(pg.Gte(f.Col("revenue"), filters.Revenue.Min.Content*FloatConstant))
-		cond.And(pg.Raw("(f.revenue::real / cr.rate) >= %c", filters.Revenue.Min.Content*FloatConstant))
-	}
-	if filters.Revenue.Max.Valid {
-		cond.And(pg.Raw("(f.revenue::real / cr.rate) <= %c", filters.Revenue.Max.Content*FloatConstant))
-	}
+	cols := make([]pg.StringEncoder, 8, len(filters.Columns)+8)
+	cols[0] = a.Col("id")
+	cols[1] = a.Col("name")
+	cols[2] = curr.Col("name")
+	cols[3] = a.Col("country_code")
+	cols[4] = pg.Col("null::float8")
+	cols[5] = pg.Col("null::float8")
+	cols[6] = pg.Col("null::timestamp")
+	cols[7] = pg.Col("null::text")
+	joins := make([]pg.QueryEncoder, 1, 5)
+	joins[0] = pg.Raw("left join %T on %c", curr, pg.Eq(curr.Col("id"), a.Col("currencyId")))
 
-	if filters.EPS.Min.Valid {
-		cond.And(pg.Gte(df.Col("eps"), filters.EPS.Min))
-	}
-	if filters.EPS.Max.Valid {
-		cond.And(pg.Lte(df.Col("eps"), filters.EPS.Max))
+	tables := map[domain.MetricSource]struct{}{
+		domain.MetricSourceCompanies:  {},
+		domain.MetricSourceCurrencies: {},
 	}
 
-	if filters.EVEBIT.Min.Valid {
-		cond.And(pg.Gte(df.Col("evebit"), filters.EVEBIT.Min))
-	}
-	if filters.EVEBIT.Max.Valid {
-		cond.And(pg.Lte(df.Col("evebit"), filters.EVEBIT.Max))
+	// Valuations at the latest price, of either the most recent fiscal year or the trailing twelve
+	// months, have the date of the price
+	if filters.Valuation == domain.ValuationCurrent || filters.Period == domain.PeriodKindTTM {
+		cols[6] = pg.Col("df.price_date")
+		joins = append(joins, screenerJoin(tables, domain.Metric{Source: domain.MetricSourceDerivedFinancials}, a, filters, rankings))
 	}
 
-	if filters.PB.Min.Valid {
-		cond.And(pg.Gte(df.Col("pb"), filters.PB.Min))
-	}
-	if filters.PB.Max.Valid {
-		cond.And(pg.Lte(df.Col("pb"), filters.PB.Max))
+	// The current valuation decides the fiscal year of everything else, so it's joined first
+	if filters.Valuation == domain.ValuationCurrent {
+		cv := CurrentValuations.Alias("cv")
+		joins = append(joins, pg.Raw("cross join lateral (select coalesce((select cv.fiscal_year from %T where %c), %c) as fiscal_year) as vy", cv, pg.Eq(cv.Col("company_id"), a.Col("id")), filters.FiscalYear))
 	}
 
-	if filters.PE.Min.Valid {
-		cond.And(pg.Gte(df.Col("pe"), filters.PE.Min))
-	}
-	if filters.PE.Max.Valid {
-		cond.And(pg.Lte(df.Col("pe"), filters.PE.Max))
-	}
+	joins = append(joins, currencyRates(filters.FXPolicy, pg.Raw(screenerFiscalYear(filters)), a.Col("currencyId")))
 
-	if filters.PS.Min.Valid {
-		cond.And(pg.Gte(df.Col("ps"), filters.PS.Min))
-	}
-	if filters.PS.Max.Valid {
-		cond.And(pg.Lte(df.Col("ps"), filters.PS.Max))
+	if filters.Currency != "" {
+		cols[4] = pg.Col("fx.flow")
+		cols[5] = pg.Col("fx.balance")
+		joins = append(joins, currencyConversion(filters.FXPolicy, filters.Currency))
 	}
 
-	if filters.OperatingMargin.Min.Valid {
-		cond.And(pg.Gte(df.Col("operating_margin"), filters.OperatingMargin.Min))
-	}
-	if filters.OperatingMargin.Max.Valid {
-		cond.And(pg.Lte(df.Col("operating_margin"), filters.OperatingMargin.Max))
+	if filters.Period == domain.PeriodKindTTM {
+		cols[7] = pg.Col("f.fiscal_year || '-' || f.period")
+		joins = append(joins, screenerJoin(tables, domain.Metric{Source: domain.MetricSourceFinancials}, a, filters, rankings))
 	}
 
-	if filters.NetMargin.Min.Valid {
-		cond.And(pg.Gte(df.Col("net_margin"), filters.NetMargin.Min))
-	}
-	if filters.NetMargin.Max.Valid {
-		cond.And(pg.Lte(df.Col("net_margin"), filters.NetMargin.Max))
-	}
+	for _, id := range filters.Columns {
+		metric, ok := domain.LookupMetric(id)
 
-	if filters.ROE.Min.Valid {
-		cond.And(pg.Gte(df.Col("roe"), filters.ROE.Min))
-	}
-	if filters.ROE.Max.Valid {
-		cond.And(pg.Lte(df.Col("roe"), filters.ROE.Max))
-	}
+		if !ok || !metric.Column {
+			return nil, nil, domain.ErrUnknownMetric.Detailed("unknown column \""+string(id)+"\"", "columns")
+		}
 
-	if filters.ROC.Min.Valid {
-		cond.And(pg.Gte(df.Col("roc"), filters.ROC.Min))
-	}
-	if filters.ROC.Max.Valid {
-		cond.And(pg.Lte(df.Col("roc"), filters.ROC.Max))
+		cols = append(cols, screenerColumn(metric, filters.Currency))
+
+		if join := screenerJoin(tables, metric, a, filters, rankings); join != nil {
+			joins = append(joins, join)
+		}
 	}
 
-	if filters.LiabilitiesToEquity.Min.Valid {
-		cond.And(pg.Gte(df.Col("liabilities_to_equity"), filters.LiabilitiesToEquity.Min))
+	if metric, ok := domain.LookupMetric(filters.OrderBy); ok {
+		if join := screenerJoin(tables, metric, a, filters, rankings); join != nil {
+			joins = append(joins, join)
+		}
 	}
-	if filters.LiabilitiesToEquity.Max.Valid {
-		cond.And(pg.Lte(df.Col("liabilities_to_equity"), filters.LiabilitiesToEquity.Max))
+
+	for metric := range filters.RangedMetrics() {
+		if join := screenerJoin(tables, metric, a, filters, rankings); join != nil {
+			joins = append(joins, join)
+		}
 	}
 
-	if filters.DebtToEBIT.Min.Valid {
-		cond.And(pg.Gte(df.Col("debt_to_ebit"), filters.DebtToEBIT.Min))
+	var err error
+
+	domain.WalkMetrics(expr, func(name string) {
+		metric, ok := domain.LookupMetric(domain.MetricID(name))
+
+		if !ok || !metric.Filterable {
+			if err == nil {
+				err = domain.ErrUnknownMetric.Detailed("unknown metric \""+name+"\"", "expression")
+			}
+			return
+		}
+
+		if join := screenerJoin(tables, metric, a, filters, rankings); join != nil {
+			joins = append(joins, join)
+		}
+	})
+
+	return pg.Columns(cols), joins, err
+}
+
+// Joins the source of a metric, unless it's already joined.
+func screenerJoin(tables map[domain.MetricSource]struct{}, metric domain.Metric, a pg.Alias, filters domain.ScreenerFilter, rankings map[domain.MetricID]pg.QueryEncoder) pg.QueryEncoder {
+	source := metric.Source
+
+	if metric.Trend != nil {
+		source = domain.MetricSource(metric.ID)
+	} else if metric.Relative != nil {
+		source = domain.MetricSource(metric.Relative.Metric + "_sector")
 	}
-	if filters.DebtToEBIT.Max.Valid {
-		cond.And(pg.Lte(df.Col("debt_to_ebit"), filters.DebtToEBIT.Max))
+
+	if _, ok := tables[source]; ok {
+		return nil
 	}
+	tables[source] = struct{}{}
 
-	if filters.DebtToAssets.Min.Valid {
-		cond.And(pg.Gte(df.Col("debt_to_assets"), filters.DebtToAssets.Min))
+	if metric.Trend != nil {
+		return screenerTrend(metric, a, screenerFiscalYear(filters))
 	}
-	if filters.DebtToAssets.Max.Valid {
-		cond.And(pg.Lte(df.Col("debt_to_assets"), filters.DebtToAssets.Max))
+
+	if metric.Relative != nil {
+		return screenerRelative(metric, a, filters)
 	}
 
-	if filters.CashConversion.Min.Valid {
-		cond.And(pg.Gte(df.Col("cash_conversion"), filters.CashConversion.Min))
+	if metric.Ranking != nil {
+		r := pg.Identifier(metric.ID)
+		return pg.Raw("left join (%T) as %T on %c", rankings[metric.ID], r, pg.Eq(r.Col("company_id"), a.Col("id")))
 	}
-	if filters.CashConversion.Max.Valid {
-		cond.And(pg.Lte(df.Col("cash_conversion"), filters.CashConversion.Max))
+
+	b := pg.Identifier(source).Alias(source.Alias())
+
+	if source == domain.MetricSourceSectors {
+		return pg.Raw("left join %T on %c", b, pg.Eq(b.Col("id"), a.Col("sectorId")))
 	}
 
-	if filters.MagicRank.Min.Valid {
-		cond.And(pg.Gte(m.Col("rank"), filters.MagicRank.Min))
+	// The trailing twelve months have the same columns as the financials, but for the latest
+	// quarter or half-year of each company
+	if source == domain.MetricSourceFinancials && filters.Period == domain.PeriodKindTTM {
+		b = TTMFinancials.Alias(source.Alias())
+		return pg.Raw("left join %T on %c", b, pg.Eq(b.Col("company_id"), a.Col("id")))
 	}
-	if filters.MagicRank.Max.Valid {
-		cond.And(pg.Lte(m.Col("rank"), filters.MagicRank.Max))
+
+	// Valuations of the trailing twelve months have the same columns as the derived financials, but
+	// for the latest price and the latest quarter or half-year of each company
+	if source == domain.MetricSourceDerivedFinancials && filters.Period == domain.PeriodKindTTM {
+		b = TTMValuations.Alias(source.Alias())
+		return pg.Raw("left join %T on %c", b, pg.Eq(b.Col("company_id"), a.Col("id")))
 	}
 
-	return cond
-}
+	// Current valuations have the same columns as the derived financials, but for the latest
+	// price and fiscal year of each company
+	if source == domain.MetricSourceDerivedFinancials && filters.Valuation == domain.ValuationCurrent {
+		b = CurrentValuations.Alias(source.Alias())
+		return pg.Raw("left join %T on %c", b, pg.Eq(b.Col("company_id"), a.Col("id")))
+	}
 
-func screenerQuery(filters domain.ScreenerFilter, a pg.Alias) (columns, []pg.QueryEncoder) {
-	curr := Currency.Alias("curr")
-	cr := QuarterlyCurrencyRates.Alias("cr")
+	// Price metrics have a row per fiscal year, as of its valuation date, and one for the latest price
+	if source == domain.MetricSourcePriceMetrics {
+		if filters.Valuation == domain.ValuationCurrent {
+			return pg.Raw("left join %T on %c", b, pg.And(pg.Eq(b.Col("company_id"), a.Col("id")), pg.Eq(b.Col("current"), true)))
+		}
 
-	cols := make([]pg.ChainedIdentifier, 4, len(filters.Columns)+4)
-	cols[0] = a.Col("id")
-	cols[1] = a.Col("name")
-	cols[2] = curr.Col("name")
-	cols[3] = a.Col("country_code")
-	joins := make([]pg.QueryEncoder, 1)
-	joins[0] = pg.Raw("left join %T on %c", cr, pg.And(pg.Eq(cr.Col("fiscal_year"), filters.FiscalYear), pg.Eq(cr.Col("currency_id"), a.Col("currencyId")), pg.Eq(cr.Col("quarter"), 1)))
-	// joins[0] = pg.Raw(`left join quarterly_currency_rates cr on cr.fiscal_year = 2024 and cr.currency_id = c."currencyId" and cr.quarter = 1`)
-	tables := make(map[pg.Identifier]struct{})
+		return pg.Raw("left join %T on %c", b, pg.And(pg.Eq(b.Col("company_id"), a.Col("id")), pg.Eq(b.Col("fiscal_year"), filters.FiscalYear), pg.Eq(b.Col("current"), false)))
+	}
 
-	m := MagicFormulaRankings.Alias("m")
-	f := Financials.Alias("f")
-	sec := Sector.Alias("sec")
-	df := DerivedFinancials.Alias("df")
-
-	for _, c := range filters.Columns {
-		switch c {
-		case domain.ScreenerColumnMagicRank:
-			cols = append(cols, m.Col("rank"))
-			if join := tryAddTable(tables, MagicFormulaRankings, a, m, filters); join != nil {
-				joins = append(joins, join)
-			}
+	return pg.Raw("left join %T on %c", b, pg.And(pg.Eq(b.Col("company_id"), a.Col("id")), pg.Eq(b.Col("fiscal_year"), pg.Col(screenerFiscalYear(filters)))))
+}
 
-		case domain.ScreenerColumnSector:
-			cols = append(cols, sec.Col("name"))
+// The fiscal year that annual sources, trends and currency rates are joined on. In current valuation
+// mode it's the fiscal year of each company's current valuation, i.e. its most recent reported one,
+// which the screener query joins as "vy". Derived metrics in trailing twelve months mode aren't
+// annual, and are of the latest period regardless.
+func screenerFiscalYear(filters domain.ScreenerFilter) string {
+	if filters.Valuation == domain.ValuationCurrent {
+		return "vy.fiscal_year"
+	}
 
-			if _, ok := tables[Sector]; ok {
-				continue
-			}
+	return strconv.Itoa(filters.FiscalYear)
+}
 
-			joins = append(joins, pg.Raw("left join %T on %c", sec, pg.Eq(sec.Col("id"), a.Col("sectorId"))))
+// Computes a trend metric over the fiscal years up until fiscalYear, an SQL expression, as a lateral
+// join named after the metric.
+func screenerTrend(metric domain.Metric, a pg.Alias, fiscalYear string) pg.QueryEncoder {
+	trend := metric.Trend
+	base, _ := domain.LookupMetric(trend.Metric)
+
+	v := "(" + base.Expr + ")::float8"
+	alias := base.Source.Alias()
+	from := string(base.Source) + " " + alias
+	years := strconv.Itoa(trend.Years)
+	first := "(" + fiscalYear + " - " + strconv.Itoa(trend.Years-1) + ")"
+	last := fiscalYear
+
+	var q string
+
+	switch trend.Kind {
+	case domain.MetricTrendCAGR:
+		first = "(" + fiscalYear + " - " + years + ")"
+		q = `select case when t.first > 0 and t.last > 0 then power(t.last / t.first, 1.0 / ` + years + `) - 1 end
+			from (
+				select
+					max(` + v + `) filter (where ` + alias + `.fiscal_year = ` + first + `) as first,
+					max(` + v + `) filter (where ` + alias + `.fiscal_year = ` + last + `) as last
+				from ` + from + `
+				where ` + alias + `.company_id = %T and ` + alias + `.fiscal_year in (` + first + `, ` + last + `)
+			) t`
+
+	case domain.MetricTrendAverage, domain.MetricTrendStdDev:
+		fn := "avg"
+
+		if trend.Kind == domain.MetricTrendStdDev {
+			fn = "stddev_samp"
+		}
 
-		// Static financials
-		case "revenue", "cost_of_revenue", "gross_operating_profit", "ebit", "net_income", "total_assets", "total_liabilities", "cash_and_equivalents", "short_term_investments", "long_term_debt", "current_debt", "equity", "operating_cash_flow", "capital_expenditures", "free_cash_flow", "number_of_shares", "ppe":
-			cols = append(cols, f.Col(c))
-			if join := tryAddTable(tables, Financials, a, f, filters); join != nil {
-				joins = append(joins, join)
-			}
+		q = `select case when count(` + v + `) = ` + years + ` then ` + fn + `(` + v + `) end
+			from ` + from + `
+			where ` + alias + `.company_id = %T and ` + alias + `.fiscal_year between ` + first + ` and ` + last
 
-		// Derived financials
-		case "eps", "pe", "evebit", "ps", "pb", "operating_margin", "net_margin", "roe", "roc", "liabilities_to_equity", "debt_to_ebit", "debt_to_assets", "cash_conversion":
-			cols = append(cols, df.Col(c))
-			if join := tryAddTable(tables, DerivedFinancials, a, df, filters); join != nil {
-				joins = append(joins, join)
-			}
-		}
+	case domain.MetricTrendStreak:
+		q = `select coalesce(min(` + last + ` - y.fiscal_year), ` + years + `)
+			from generate_series(` + first + `, ` + last + `) y(fiscal_year)
+			left join ` + from + ` on ` + alias + `.company_id = %T and ` + alias + `.fiscal_year = y.fiscal_year
+			where not coalesce(` + v + ` > 0, false)`
 	}
 
-	if domain.HasValid(filters.CapitalExpenditures, filters.EBIT, filters.Equity, filters.GrossOperatingProfit, filters.NetIncome, filters.OperatingCashFlow, filters.Revenue) {
-		if join := tryAddTable(tables, Financials, a, f, filters); join != nil {
-			joins = append(joins, join)
-		}
-	}
+	return pg.Raw("left join lateral (select ("+q+")::float8 as value) as %T on true", a.Col("id"), pg.Identifier(metric.ID))
+}
 
-	if domain.HasValid(filters.EPS,
-		filters.EVEBIT,
-		filters.PB,
-		filters.PE,
-		filters.PS,
-		filters.OperatingMargin,
-		filters.NetMargin,
-		filters.ROE,
-		filters.ROC,
-		filters.LiabilitiesToEquity,
-		filters.DebtToEBIT,
-		filters.DebtToAssets,
-		filters.CashConversion,
-		filters.MagicRank) {
-		if join := tryAddTable(tables, DerivedFinancials, a, df, filters); join != nil {
-			joins = append(joins, join)
-		}
+// Ranks a metric within each sector for the fiscal year, as a join named after the metric with a
+// "_sector" suffix. In trailing twelve months and current valuation mode it's ranked among the
+// valuations at the latest price instead, which are of each company's most recent period.
+func screenerRelative(metric domain.Metric, a pg.Alias, filters domain.ScreenerFilter) pg.QueryEncoder {
+	base, _ := domain.LookupMetric(metric.Relative.Metric)
+	alias := base.Source.Alias()
+	v := "(" + base.Expr + ")"
+	partition := `c."sectorId"`
+	source := pg.Identifier(base.Source)
+	cond := pg.And(pg.Raw(v + " is not null"))
+
+	if filters.Period == domain.PeriodKindTTM {
+		source = TTMValuations
+	} else if filters.Valuation == domain.ValuationCurrent {
+		source = CurrentValuations
+	} else {
+		cond.And(pg.Eq(pg.Col(alias+".fiscal_year"), filters.FiscalYear))
+	}
+
+	switch filters.SectorScope {
+	case domain.SectorScopeCountry:
+		partition += ", c.country_code"
+	case domain.SectorScopeMarketPlace:
+		partition += ", c.market_place_code"
+	}
+
+	return pg.Raw(`left join (
+			select
+				`+alias+`.company_id,
+				percent_rank() over (w order by `+v+`) as pct,
+				(`+v+` - avg(`+v+`) over w) / nullif(stddev_samp(`+v+`) over w, 0) as z
+			from %T
+			inner join companies c on c.id = `+alias+`.company_id
+			where %c
+			window w as (partition by `+partition+`)
+		) as %T on %c`, source.Alias(alias), cond, pg.Identifier(base.ID+"_sector"), pg.Eq(pg.Identifier(base.ID+"_sector").Col("company_id"), a.Col("id")))
+}
+
+// The selected value of a metric, with monetary values converted to the display currency if any.
+// Values stay in the reporting currency unless there are both rates to convert them with, so that
+// the row is either converted as a whole or not at all.
+func screenerColumn(metric domain.Metric, currency string) pg.StringEncoder {
+	if currency == "" || !metric.Currency {
+		return pg.Col(metric.Expr)
 	}
 
-	return columns{cols}, joins
-}
+	rate := "(case when fx.flow is not null and fx.balance is not null then " + currencyRate(metric, "fx") + " end)"
 
-func tryAddTable(tables map[pg.Identifier]struct{}, identifier pg.Identifier, a1 pg.Alias, a2 pg.Alias, filters domain.ScreenerFilter) pg.QueryEncoder {
-	if _, ok := tables[identifier]; ok {
-		return nil
+	if _, ok := metric.Target(&domain.Screener{}).(*domain.Nullable[int64]); ok {
+		return pg.Col("coalesce(round(" + metric.Expr + " * " + rate + ")::bigint, " + metric.Expr + ")")
 	}
-	tables[identifier] = struct{}{}
 
-	return pg.Raw("left join %T on %c", a2, pg.And(pg.Eq(a2.Col("company_id"), a1.Col("id")), pg.Eq(a2.Col("fiscal_year"), filters.FiscalYear)))
+	return pg.Col("coalesce((" + metric.Expr + " * " + rate + ")::float8, (" + metric.Expr + ")::float8)")
 }
 
-func screenerScanColumns(screener *domain.Screener, cols []string) []any {
-	scans := make([]any, 4, len(cols)+4)
+func screenerScanColumns(screener *domain.Screener, cols []domain.MetricID) []any {
+	scans := make([]any, 8, len(cols)+8)
 
 	scans[0] = &screener.CompanyId
 	scans[1] = &screener.Name
 	scans[2] = &screener.Currency
 	scans[3] = &screener.CountryCode
+	scans[4] = &screener.ExchangeRate
+	scans[5] = &screener.BalanceExchangeRate
+	scans[6] = &screener.PriceDate
+	scans[7] = &screener.TTMThrough
 
-	//TODO: Consider replacing this with reflection or cache a reflected domain.Screener to set values.
-	for _, c := range cols {
-		switch c {
-		case domain.ScreenerColumnMagicRank:
-			scans = append(scans, &screener.MagicRank)
-		case domain.ScreenerColumnSector:
-			scans = append(scans, &screener.Sector)
-
-		// Static financials
-		case domain.ScreenerColumnRevenue:
-			scans = append(scans, &screener.Revenue)
-		case "cost_of_revenue":
-			scans = append(scans, &screener.CostOfRevenue)
-		case "gross_operating_profit":
-			scans = append(scans, &screener.GrossOperatingProfit)
-		case "ebit":
-			scans = append(scans, &screener.EBIT)
-		case "net_income":
-			scans = append(scans, &screener.NetIncome)
-		case "total_assets":
-			scans = append(scans, &screener.TotalAssets)
-		case "total_liabilities":
-			scans = append(scans, &screener.TotalLiabilities)
-		case "cash_and_equivalents":
-			scans = append(scans, &screener.CashAndEquivalents)
-		case "short_term_investments":
-			scans = append(scans, &screener.ShortTermInvestments)
-		case "long_term_debt":
-			scans = append(scans, &screener.LongTermDebt)
-		case "current_debt":
-			scans = append(scans, &screener.CurrentDebt)
-		case "equity":
-			scans = append(scans, &screener.Equity)
-		case "operating_cash_flow":
-			scans = append(scans, &screener.OperatingCashFlow)
-		case "capital_expenditures":
-			scans = append(scans, &screener.CapitalExpenditures)
-		case "free_cash_flow":
-			scans = append(scans, &screener.FreeCashFlow)
-		case "number_of_shares":
-			scans = append(scans, &screener.NumberOfShares)
-		case "ppe":
-			scans = append(scans, &screener.PPE)
-
-		// Derived financials
-		case "eps":
-			scans = append(scans, &screener.EPS)
-		case "pe":
-			scans = append(scans, &screener.PE)
-		case "evebit":
-			scans = append(scans, &screener.EVEBIT)
-		case "ps":
-			scans = append(scans, &screener.PS)
-		case "pb":
-			scans = append(scans, &screener.PB)
-		case "operating_margin":
-			scans = append(scans, &screener.OperatingMargin)
-		case "net_margin":
-			scans = append(scans, &screener.NetMargin)
-		case "roe":
-			scans = append(scans, &screener.ROE)
-		case "roc":
-			scans = append(scans, &screener.ROC)
-		case "liabilities_to_equity":
-			scans = append(scans, &screener.LiabilitiesToEquity)
-		case "debt_to_ebit":
-			scans = append(scans, &screener.DebtToEbit)
-		case "debt_to_assets":
-			scans = append(scans, &screener.DebtToAssets)
-		case "cash_conversion":
-			scans = append(scans, &screener.CashConversion)
-		}
+	for _, id := range cols {
+		metric, _ := domain.LookupMetric(id)
+		scans = append(scans, metric.Target(screener))
 	}
 
 	return scans
@@ -485,16 +594,13 @@ func screenerScanColumns(screener *domain.Screener, cols []string) []any {
 
 // CountMagicRanks implements port.Screener
 func (s screenerStore) CountMagicRanks(ctx context.Context, filters domain.MagicRankFilter) (count int, err error) {
-	m := MagicFormulaRankings.Alias("m")
-
-	cond := MagicRanksFilter(filters, m)
-
 	row := s.db.QueryRow(ctx, `
 			select
 				count(*)
-			from %T
+			from (%T) m
+			inner join %T c on c.id = m.company_id
 			where %c
-		`, m, cond)
+		`, magicRankQuery(filters), Company, MagicRanksFilter(filters, Company.Alias("c")))
 
 	err = row.Scan(&count)
 
@@ -504,11 +610,15 @@ func (s screenerStore) CountMagicRanks(ctx context.Context, filters domain.Magic
 // IterateMagicRanks implements port.Screener
 func (s screenerStore) IterateMagicRanks(ctx context.Context, filters domain.MagicRankFilter) iter.Seq2[*domain.MagicRank, error] {
 	return func(yield func(*domain.MagicRank, error) bool) {
-		m := MagicFormulaRankings.Alias("m")
-		cond := pg.And()
+		orderBy := pg.Col("m.rank")
 
-		if filters.FiscalYear != 0 {
-			cond.And(pg.Eq(m.Col("fiscal_year"), filters.FiscalYear))
+		switch filters.OrderBy {
+		case "roc":
+			orderBy = pg.Col("m.roc")
+		case "earningsYield":
+			orderBy = pg.Col("m.yield")
+		case "name":
+			orderBy = pg.Col("c.name")
 		}
 
 		rows, err := s.db.Query(ctx, `
@@ -521,10 +631,13 @@ func (s screenerStore) IterateMagicRanks(ctx context.Context, filters domain.Mag
 				m.roc_rank,
 				m.yield_rank,
 				m.rank
-			from %T
-			left join %T c on c.id = m.company_id
-			WHERE %c
-		`, m, Company, cond)
+			from (%T) m
+			inner join %T c on c.id = m.company_id
+			where %c
+			order by %T, c.name
+			offset %d
+			limit %d
+		`, magicRankQuery(filters), Company, MagicRanksFilter(filters, Company.Alias("c")), pg.Order(orderBy, filters.Order), filters.Offset, filters.Limit)
 
 		if err != nil {
 			yield(nil, err)
@@ -557,8 +670,308 @@ func (s screenerStore) IterateMagicRanks(ctx context.Context, filters domain.Mag
 	}
 }
 
+// Filters the ranked companies. The ranks aren't affected, as the companies are filtered after
+// they have been ranked.
 func MagicRanksFilter(filters domain.MagicRankFilter, a pg.Alias) pg.QueryEncoder {
 	cond := pg.And()
 
+	if len(filters.Include) > 0 {
+		cond.And(pg.In(a.Col("id"), filters.Include))
+	}
+
+	if filters.Search != "" {
+		cond.And(pg.Search(a.Col("ts"), filters.Search, pg.SearchOptions{
+			Preprocessor: pg.PrefixSearch,
+		}))
+	}
+
+	if filters.Top > 0 {
+		cond.And(pg.Raw("m.rank <= %c", filters.Top))
+	}
+
 	return cond
 }
+
+// Ranks companies by the magic formula, like the magic_formula_rankings view, but with the variants
+// of the filter applied to the universe before ranking.
+func magicRankQuery(filters domain.MagicRankFilter) pg.QueryEncoder {
+	c := Company.Alias("c")
+	sec := Sector.Alias("sec")
+	cond := pg.And(
+		pg.Eq(pg.Col("f.fiscal_year"), filters.FiscalYear),
+		pg.Raw("s.average > 0"),
+	)
+
+	if len(filters.Countries) > 0 {
+		cond.And(pg.In(c.Col("country_code"), filters.Countries))
+	}
+
+	if filters.ExcludeFinancials {
+		cond.And(pg.Raw("%T not ilike all(%c)", sec.Col("name"), domain.FinancialSectorPatterns))
+	}
+
+	if filters.ExcludeUtilities {
+		cond.And(pg.Raw("%T not ilike all(%c)", sec.Col("name"), domain.UtilitySectorPatterns))
+	}
+
+	// Market cap and enterprise value in millions of the base currency, like the derived financials
+	marketCap := "(f.number_of_shares * s.average::float / 1000000 / " + strconv.Itoa(FloatConstant) + " / cr.balance)"
+	ev := "((f.number_of_shares * s.average::float / 1000000 + (f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::float) / " + strconv.Itoa(FloatConstant) + " / cr.balance)"
+
+	if filters.MinMarketCap > 0 {
+		cond.And(pg.Raw(marketCap+" >= %c::float8", filters.MinMarketCap))
+	}
+
+	if filters.MaxMarketCap > 0 {
+		cond.And(pg.Raw(marketCap+" <= %c::float8", filters.MaxMarketCap))
+	}
+
+	if filters.MinEnterpriseValue > 0 {
+		cond.And(pg.Raw(ev+" >= %c::float8", filters.MinEnterpriseValue))
+	}
+
+	if filters.MaxEnterpriseValue > 0 {
+		cond.And(pg.Raw(ev+" <= %c::float8", filters.MaxEnterpriseValue))
+	}
+
+	partition := "fiscal_year"
+
+	if filters.PerCountry {
+		partition += ", country_code"
+	}
+
+	return pg.Raw(`
+		with
+			calcs as (
+				select
+					f.company_id,
+					f.fiscal_year,
+					c.country_code,
+					f.ebit::numeric / nullif(f.ppe + f.total_assets - f.total_liabilities, 0) as roc,
+					f.ebit / nullif((f.number_of_shares * s.average + f.long_term_debt + f.current_debt - f.short_term_investments - f.cash_and_equivalents)::numeric / 1000000, 0) as yield
+				from financials f
+				inner join adjusted_shares s on f.company_id = s.company_id and s.date = to_date((f.fiscal_year + 1) || '-01-02', 'YYYY-MM-DD')
+				inner join %T on c.id = f.company_id
+				left join %T on sec.id = c."sectorId"
+				%T
+				where %c
+			),
+			ranks as (
+				select
+					c.*,
+					rank() over (partition by `+partition+` order by c.roc desc) as roc_rank,
+					rank() over (partition by `+partition+` order by c.yield desc) as yield_rank
+				from calcs c
+				where c.roc is not null and c.yield is not null
+			)
+		select
+			r.company_id,
+			r.fiscal_year,
+			r.roc::float8 as roc,
+			r.yield::float8 as yield,
+			r.roc_rank,
+			r.yield_rank,
+			rank() over (partition by `+partition+` order by r.roc_rank + r.yield_rank asc) as rank
+		from ranks r
+	`, c, sec, currencyRates(domain.FXPolicyStandard, pg.Raw("%c", filters.FiscalYear), c.Col("currencyId")), cond)
+}
+
+// CountAggregates implements port.Screener
+func (s screenerStore) CountAggregates(ctx context.Context, filters domain.AggregateFilter) (count int, err error) {
+	q, err := aggregateQuery(filters)
+
+	if err != nil {
+		return
+	}
+
+	row := s.db.QueryRow(ctx, `
+			select
+				count(*)
+			from (%T) a
+		`, q)
+
+	err = row.Scan(&count)
+
+	return
+}
+
+// IterateAggregates implements port.Screener
+func (s screenerStore) IterateAggregates(ctx context.Context, filters domain.AggregateFilter) iter.Seq2[*domain.Aggregate, error] {
+	return func(yield func(*domain.Aggregate, error) bool) {
+		q, err := aggregateQuery(filters)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		rows, err := s.db.Query(ctx, `
+			select
+				a.fiscal_year,
+				a.grp,
+				a.name,
+				a.count,
+				a.nulls,
+				a.outliers,
+				a.mean,
+				a.median,
+				a.p25,
+				a.p75,
+				a.weighted_mean
+			from (%T) a
+			order by a.fiscal_year, a.name, a.grp
+			offset %d
+			limit %d
+		`, q, filters.Offset, filters.Limit)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var aggregate domain.Aggregate
+
+			if err = rows.Scan(
+				&aggregate.FiscalYear,
+				&aggregate.Group,
+				&aggregate.Name,
+				&aggregate.Count,
+				&aggregate.Nulls,
+				&aggregate.Outliers,
+				&aggregate.Mean,
+				&aggregate.Median,
+				&aggregate.P25,
+				&aggregate.P75,
+				&aggregate.WeightedMean,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&aggregate, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Aggregates a derived metric per group and fiscal year. The values beyond the outlier percentiles
+// of each group are clamped to them when winsorizing, or left out when trimming, before computing
+// the statistics. Nulls are counted but otherwise left out.
+func aggregateQuery(filters domain.AggregateFilter) (pg.QueryEncoder, error) {
+	metric, ok := domain.LookupMetric(filters.Metric)
+
+	if !ok || metric.Source != domain.MetricSourceDerivedFinancials {
+		return nil, domain.ErrUnknownMetric.Detailed("cannot aggregate \""+string(filters.Metric)+"\"", "metric")
+	}
+
+	c := Company.Alias("c")
+	cond := pg.And()
+
+	if filters.FromYear > 0 {
+		cond.And(pg.Gte(pg.Col("df.fiscal_year"), filters.FromYear))
+	}
+
+	if filters.ToYear > 0 {
+		cond.And(pg.Lte(pg.Col("df.fiscal_year"), filters.ToYear))
+	}
+
+	if len(filters.Sectors) > 0 {
+		cond.And(pg.In(c.Col("sectorId"), filters.Sectors))
+	}
+
+	if len(filters.Countries) > 0 {
+		cond.And(pg.In(c.Col("country_code"), filters.Countries))
+	}
+
+	if len(filters.MarketPlaces) > 0 {
+		cond.And(pg.In(c.Col("market_place_code"), filters.MarketPlaces))
+	}
+
+	// Companies without a sector are grouped together rather than left out
+	group, name := `coalesce(c."sectorId"::text, '')`, "coalesce(sec.name, 'Unassigned')"
+
+	switch filters.GroupBy {
+	case domain.AggregateGroupCountry:
+		group, name = "c.country_code::text", "c.country_code::text"
+	case domain.AggregateGroupMarketPlace:
+		group, name = "c.market_place_code::text", "c.market_place_code::text"
+	}
+
+	tail := 0.0
+
+	if filters.Outliers != domain.OutlierPolicyNone {
+		tail = float64(filters.OutlierPercent) / 100
+	}
+
+	value := "o.value"
+
+	switch filters.Outliers {
+	case domain.OutlierPolicyWinsorize:
+		value = "least(greatest(o.value, o.lo), o.hi)"
+	case domain.OutlierPolicyTrim:
+		value = "case when o.outlier then null else o.value end"
+	}
+
+	return pg.Raw(`
+		with
+			vals as (
+				select
+					df.fiscal_year,
+					`+group+` as grp,
+					`+name+` as name,
+					`+screenerValueExpr(metric)+`::float8 as value,
+					df.market_cap::float8 / `+strconv.Itoa(FloatConstant)+` / cr.balance as weight
+				from %T df
+				inner join %T on c.id = df.company_id
+				left join %T sec on sec.id = c."sectorId"
+				%T
+				where %c
+			),
+			bounds as (
+				select
+					v.fiscal_year,
+					v.grp,
+					percentile_cont(%c::float8) within group (order by v.value) as lo,
+					percentile_cont(1 - %c::float8) within group (order by v.value) as hi
+				from vals v
+				group by v.fiscal_year, v.grp
+			),
+			outliers as (
+				select
+					v.*,
+					b.lo,
+					b.hi,
+					coalesce(v.value < b.lo or v.value > b.hi, false) as outlier
+				from vals v
+				inner join bounds b on b.fiscal_year = v.fiscal_year and b.grp is not distinct from v.grp
+			),
+			adjusted as (
+				select
+					o.fiscal_year,
+					o.grp,
+					o.name,
+					o.outlier,
+					`+value+` as value,
+					case when o.weight > 0 then o.weight end as weight
+				from outliers o
+			)
+		select
+			a.fiscal_year,
+			a.grp,
+			min(a.name) as name,
+			count(a.value) as count,
+			count(*) filter (where not a.outlier) - count(a.value) filter (where not a.outlier) as nulls,
+			count(*) filter (where a.outlier) as outliers,
+			avg(a.value) as mean,
+			percentile_cont(0.5) within group (order by a.value) as median,
+			percentile_cont(0.25) within group (order by a.value) as p25,
+			percentile_cont(0.75) within group (order by a.value) as p75,
+			sum(a.value * a.weight) / nullif(sum(a.weight) filter (where a.value is not null), 0) as weighted_mean
+		from adjusted a
+		group by a.fiscal_year, a.grp
+	`, DerivedFinancials, c, Sector, currencyRates(domain.FXPolicyStandard, pg.Raw("df.fiscal_year"), c.Col("currencyId")), cond, tail, tail), nil
+}
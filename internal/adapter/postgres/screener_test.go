@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/rs/xid"
+	"github.com/webmafia/pg"
+)
+
+func TestScreenerQueryFiscalYear(t *testing.T) {
+	columns := []domain.MetricID{"revenue", "pe", "revenue_cagr_3y", "pe_sector_pct"}
+
+	tests := []struct {
+		name      string
+		valuation domain.Valuation
+		period    domain.PeriodKind
+		want      []string
+	}{
+		{
+			name:      "historical",
+			valuation: domain.ValuationHistorical,
+			want: []string{
+				"where r.fiscal_year = 2024 and r.currency_id",
+				`"f"."fiscal_year" = 2024`,
+				`"df"."fiscal_year" = 2024`,
+				"f.fiscal_year in ((2024 - 3), 2024)",
+				`from "derived_financials" AS "df"`,
+			},
+		},
+		{
+			name:      "current",
+			valuation: domain.ValuationCurrent,
+			want: []string{
+				`left join "current_valuations" AS "df"`,
+				`(select cv.fiscal_year from "current_valuations" AS "cv" where "cv"."company_id" = "c"."id"), $1) as fiscal_year) as vy`,
+				"where r.fiscal_year = vy.fiscal_year and r.currency_id",
+				`"f"."fiscal_year" = vy.fiscal_year`,
+				"f.fiscal_year in ((vy.fiscal_year - 3), vy.fiscal_year)",
+				`from "current_valuations" AS "df"`,
+			},
+		},
+		{
+			name:      "trailing twelve months",
+			valuation: domain.ValuationHistorical,
+			period:    domain.PeriodKindTTM,
+			want: []string{
+				"where r.fiscal_year = 2024 and r.currency_id",
+				`left join "ttm_financials" AS "f" on "f"."company_id" = "c"."id"`,
+				`left join "ttm_valuations" AS "df" on "df"."company_id" = "c"."id"`,
+				"f.fiscal_year in ((2024 - 3), 2024)",
+				`from "ttm_valuations" AS "df"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := domain.ScreenerFilter{FiscalYear: 2024, Valuation: tt.valuation, Period: tt.period, Columns: columns}
+			_, joins, err := screenerQuery(filters, nil, Company.Alias("c"), nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, _ := encodeQuery(pg.Multi(joins))
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("screenerQuery() joins = %s, want %s", got, want)
+				}
+			}
+
+			// The year is decided before the rates and sources that are joined on it
+			if i, j := strings.Index(got, " as vy"), strings.Index(got, " as cr on true"); tt.valuation == domain.ValuationCurrent && i > j {
+				t.Errorf("screenerQuery() joins the rates before the current valuation year: %s", got)
+			}
+		})
+	}
+}
+
+func TestScreenerKeysetMoney(t *testing.T) {
+	metric, _ := domain.LookupMetric("revenue")
+	orderBy, err := screenerOrderBy(metric.ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is float8, so that it's compared as exactly what it's selected as
+	if got, _ := encodeQuery(pg.Raw("%T", orderBy)); got != "(f.revenue::float8 / 100 / cr.flow)" {
+		t.Errorf("screenerOrderBy(%q) = %s, want a float8 key", metric.ID, got)
+	}
+
+	cursor, err := domain.ParseScreenerCursor(screenerCursor(1.1, xid.New()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, args := encodeQuery(screenerKeyset(orderBy, "asc", cursor)); !strings.Contains(got, "(f.revenue::float8 / 100 / cr.flow) > $1") || args[0] != 1.1 {
+		t.Errorf("screenerKeyset() = %s %v, want the key compared with 1.1", got, args)
+	}
+}
+
+// Pages through keys as Postgres would compare them with the keyset, which must return every row
+// exactly once, including rows that tie on fractional keys.
+func TestScreenerCursorPaging(t *testing.T) {
+	type row struct {
+		key any
+		id  xid.ID
+	}
+
+	keys := []any{
+		110.0 / 100 / 1.0, 110.0 / 100 / 1.0, 110.0 / 100 / 1.0, // Tied fractional money values
+		0.1 + 0.2, 0.3, 2.0, 2.0, 1e21,
+		float32(1.1), float32(1.1), // Real keys, which are compared as float8
+	}
+
+	float := func(v any) float64 {
+		switch v := v.(type) {
+		case float32:
+			return float64(v)
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		}
+
+		t.Fatalf("unexpected key %T", v)
+		return 0
+	}
+
+	rows := make([]row, len(keys))
+
+	for i, key := range keys {
+		rows[i] = row{key: key, id: xid.New()}
+	}
+
+	// As ordered by the screener: by the key, and then by company
+	slices.SortFunc(rows, func(a, b row) int {
+		return cmp.Or(cmp.Compare(float(a.key), float(b.key)), a.id.Compare(b.id))
+	})
+
+	const limit = 2
+	var seen []xid.ID
+	var cursor *domain.ScreenerCursor
+
+	for len(seen) < len(rows)+limit {
+		var page []row
+
+		for _, r := range rows {
+			if cursor != nil {
+				k, c := float(r.key), float(cursor.Value)
+
+				if !(k > c || k == c && r.id.Compare(cursor.ID) > 0) {
+					continue
+				}
+			}
+
+			if page = append(page, r); len(page) == limit {
+				break
+			}
+		}
+
+		if len(page) == 0 {
+			break
+		}
+
+		for _, r := range page {
+			seen = append(seen, r.id)
+		}
+
+		last := page[len(page)-1]
+		c, err := domain.ParseScreenerCursor(screenerCursor(last.key, last.id))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cursor = &c
+	}
+
+	if len(seen) != len(rows) {
+		t.Fatalf("paged %d rows, want %d", len(seen), len(rows))
+	}
+
+	for i, r := range rows {
+		if seen[i] != r.id {
+			t.Errorf("row %d is %s, want %s", i, seen[i], r.id)
+		}
+	}
+}
+
+func TestScreenerColumnConversion(t *testing.T) {
+	for _, id := range []domain.MetricID{"revenue", "total_assets", "market_cap"} {
+		t.Run(string(id), func(t *testing.T) {
+			metric, _ := domain.LookupMetric(id)
+			got, _ := encodeQuery(pg.Raw("%T", screenerColumn(metric, "EUR")))
+
+			// Either both rates or none, like the display currency of the row
+			if !strings.Contains(got, "case when fx.flow is not null and fx.balance is not null then "+currencyRate(metric, "fx")+" end") {
+				t.Errorf("screenerColumn(%q) = %s, want it converted only when both rates exist", id, got)
+			}
+
+			if !strings.HasPrefix(got, "coalesce(") {
+				t.Errorf("screenerColumn(%q) = %s, want a fallback to the reporting currency", id, got)
+			}
+		})
+	}
+
+	metric, _ := domain.LookupMetric("pe")
+
+	if got, _ := encodeQuery(pg.Raw("%T", screenerColumn(metric, "EUR"))); got != metric.Expr {
+		t.Errorf("screenerColumn(%q) = %s, want it left as it is", metric.ID, got)
+	}
+}
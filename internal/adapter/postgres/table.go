@@ -7,8 +7,26 @@ const (
 	Currency               pg.Identifier = "currencies"
 	Company                pg.Identifier = "companies"
 	Financials             pg.Identifier = "financials"
+	InterimFinancials      pg.Identifier = "interim_financials"
+	TTMFinancials          pg.Identifier = "ttm_financials"
 	DerivedFinancials      pg.Identifier = "derived_financials"
+	FinancialScores        pg.Identifier = "financial_scores"
 	Share                  pg.Identifier = "shares"
+	AdjustedShares         pg.Identifier = "adjusted_shares"
+	CorporateActions       pg.Identifier = "corporate_actions"
+	PriceIngestions        pg.Identifier = "price_ingestions"
+	Dividends              pg.Identifier = "dividends"
+	DividendMetrics        pg.Identifier = "dividend_metrics"
+	TotalReturns           pg.Identifier = "total_returns"
+	PriceMetrics           pg.Identifier = "price_metrics"
 	MagicFormulaRankings   pg.Identifier = "magic_formula_rankings"
+	CurrentValuations      pg.Identifier = "current_valuations"
+	TTMValuations          pg.Identifier = "ttm_valuations"
 	QuarterlyCurrencyRates pg.Identifier = "quarterly_currency_rates"
+	AnnualCurrencyRates    pg.Identifier = "annual_currency_rates"
+	SavedScreens           pg.Identifier = "saved_screens"
+	ScreenSnapshots        pg.Identifier = "screen_snapshots"
+	ScreenSnapshotMembers  pg.Identifier = "screen_snapshot_members"
+	Rankings               pg.Identifier = "rankings"
+	ViewRefreshes          pg.Identifier = "view_refreshes"
 )
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"iter"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/webmafia/pg"
+)
+
+// Materialized views, in the order they are refreshed.
+var materializedViews = []pg.Identifier{
+	DerivedFinancials,
+	FinancialScores,
+	MagicFormulaRankings,
+	TTMFinancials,
+	CurrentValuations,
+	TTMValuations,
+	DividendMetrics,
+	TotalReturns,
+	PriceMetrics,
+}
+
+type viewStore struct {
+	db
+}
+
+func NewView(pool *pg.DB) port.View {
+	return viewStore{
+		db: db{pool},
+	}
+}
+
+// RefreshViews implements port.View
+func (s viewStore) RefreshViews(ctx context.Context) (err error) {
+	for _, view := range materializedViews {
+		// Concurrent refreshes don't block reads, but require a unique index on the view
+		if _, err = s.db.Exec(ctx, `refresh materialized view concurrently %T`, view); err != nil {
+			return
+		}
+
+		if _, err = s.db.Exec(ctx, `
+			insert into %T (name, refreshed)
+			values (%c, now())
+			on conflict (name) do update set refreshed = excluded.refreshed
+		`, ViewRefreshes, string(view)); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// IterateViewRefreshes implements port.View
+func (s viewStore) IterateViewRefreshes(ctx context.Context) iter.Seq2[*domain.ViewRefresh, error] {
+	return func(yield func(*domain.ViewRefresh, error) bool) {
+		rows, err := s.db.Query(ctx, `
+			select
+				v.name,
+				v.refreshed
+			from %T v
+			order by v.name
+		`, ViewRefreshes)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var refresh domain.ViewRefresh
+
+			if err = rows.Scan(
+				&refresh.Name,
+				&refresh.Refreshed,
+			); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&refresh, nil) {
+				return
+			}
+		}
+	}
+}
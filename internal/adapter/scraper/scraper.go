@@ -3,6 +3,7 @@ package scraper
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,25 +32,32 @@ type scraper struct {
 	env           *env.Environment
 	currencyStore port.Currency
 	companyStore  port.Company
+	priceFeed     port.PriceFeed
 }
 
 var names = map[string]string{
 	"Revenue":                "revenue",
-	"Cost of Revenue":        "costOfRevenue",
-	"Gross Operating Profit": "grossOperatingProfit",
+	"Cost of Revenue":        "cost_of_revenue",
+	"Gross Operating Profit": "gross_operating_profit",
 	"Operating income before interest and taxes": "ebit",
-	"Net Income":                        "netIncome",
-	"Cash and cash equivalents":         "cashAndEquivalents",
-	"Short-term investments":            "shortTermInvestments",
-	"Total Assets":                      "totalAssets",
-	"Long Term Debt":                    "longTermDebt",
-	"Current Debt":                      "currentDebt",
-	"Total Liabilities":                 "totalLiabilities",
-	"Total stockholders' equity":        "equity",
-	"Operating Cash Flow":               "operatingCashFlow",
-	"Capital Expenditure":               "capitalExpenditures",
-	"Free Cash Flow":                    "freeCashFlow",
-	"Not property, plant and equipment": "ppe",
+	"Net Income":                          "net_income",
+	"Cash and cash equivalents":           "cash_and_equivalents",
+	"Short-term investments":              "short_term_investments",
+	"Total Assets":                        "total_assets",
+	"Long Term Debt":                      "long_term_debt",
+	"Current Debt":                        "current_debt",
+	"Total Liabilities":                   "total_liabilities",
+	"Total stockholders' equity":          "equity",
+	"Operating Cash Flow":                 "operating_cash_flow",
+	"Capital Expenditure":                 "capital_expenditures",
+	"Free Cash Flow":                      "free_cash_flow",
+	"Not property, plant and equipment":   "ppe",
+	"Total current assets":                "current_assets",
+	"Total current liabilities":           "current_liabilities",
+	"Retained earnings":                   "retained_earnings",
+	"Receivables":                         "receivables",
+	"Depreciation & amortization":         "depreciation",
+	"Selling, general and administrative": "sga",
 }
 
 func setFieldByJSONTag(data interface{}, jsonTag string, value any) error {
@@ -69,6 +77,16 @@ func setFieldByJSONTag(data interface{}, jsonTag string, value any) error {
 				return fmt.Errorf("cannot set field %s", field.Name)
 			}
 
+			// Nullable line items are left null when there's no value
+			if scanner, ok := fieldValue.Addr().Interface().(sql.Scanner); ok {
+				return scanner.Scan(value)
+			}
+
+			if value == nil {
+				fieldValue.SetZero()
+				return nil
+			}
+
 			val := reflect.ValueOf(value)
 
 			// Handle assignability or convertibility
@@ -86,7 +104,7 @@ func setFieldByJSONTag(data interface{}, jsonTag string, value any) error {
 	return nil // or error if field not found
 }
 
-func NewScraper(ctx context.Context, env *env.Environment, currencyStore port.Currency, companyStore port.Company) port.Scraper {
+func NewScraper(ctx context.Context, env *env.Environment, currencyStore port.Currency, companyStore port.Company, priceFeed port.PriceFeed) port.Scraper {
 	// c := colly.NewCollector(
 	// 	colly.Async(true),
 	// )
@@ -115,6 +133,7 @@ func NewScraper(ctx context.Context, env *env.Environment, currencyStore port.Cu
 		env:           env,
 		currencyStore: currencyStore,
 		companyStore:  companyStore,
+		priceFeed:     priceFeed,
 	}
 }
 
@@ -298,16 +317,18 @@ func (s scraper) GetCompanyFinancials(ctx context.Context, companies []domain.Co
 									}
 
 									filledTags[tag] = struct{}{}
-									var v float64
+									var value any
 
 									if row[i+1] != "" && row[i+1] != "-" {
+										var v float64
 										formatted := strings.ReplaceAll(row[i+1], ",", "")
 										if v, err = strconv.ParseFloat(formatted, 64); err != nil {
 											return false
 										}
+										value = int64(v * 100)
 									}
 
-									if err = setFieldByJSONTag(&financials[i].StaticData, tag, int(v*100)); err != nil {
+									if err = setFieldByJSONTag(&financials[i].StaticData, tag, value); err != nil {
 										return false
 									}
 								}
@@ -371,7 +392,7 @@ func toSlug(input string) string {
 func (s scraper) GetCompanyShares(ctx context.Context, companies []domain.Company) iter.Seq2[*domain.Share, error] {
 	return func(yield func(*domain.Share, error) bool) {
 		completedCompanies := make(map[xid.ID]struct{})
-		for c, err := range s.companyStore.IterateShares(ctx, domain.CompanyFilter{}) {
+		for c, err := range s.companyStore.IterateShares(ctx, domain.ShareFilter{}) {
 			if err != nil {
 				yield(nil, err)
 				return
@@ -391,7 +412,7 @@ func (s scraper) GetCompanyShares(ctx context.Context, companies []domain.Compan
 					continue
 				}
 				completed = false
-				if err := getShare(c.ID, c.OrderbookID, pastMonth, now, yield); err != nil {
+				if err := s.getShare(ctx, c.ID, c.OrderbookID, pastMonth, now, yield); err != nil {
 					yield(nil, err)
 					return
 				}
@@ -426,7 +447,7 @@ func (s scraper) GetCompanySharesByFinancials(ctx context.Context, companies []d
 					company = c
 				}
 			}
-			if err := getShare(company.ID, company.OrderbookID, from, to, yield); err != nil {
+			if err := s.getShare(ctx, company.ID, company.OrderbookID, from, to, yield); err != nil {
 				yield(nil, err)
 				return
 			}
@@ -435,57 +456,14 @@ func (s scraper) GetCompanySharesByFinancials(ctx context.Context, companies []d
 	}
 }
 
-func getShare(companyId xid.ID, externalId string, from time.Time, to time.Time, yield func(*domain.Share, error) bool) (err error) {
-	req, _ := http.NewRequest("GET", "https://api.nasdaq.com/api/nordic/instruments/"+externalId+"/chart/download?assetClass=SHARES&fromDate="+from.Format(time.DateOnly)+"&toDate="+to.Format(time.DateOnly), nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/135.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Referer", "https://nasdaq.com")
-
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("bad status: " + resp.Status)
-	}
-
-	var data domain.RawRoot
-	if err = json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return
-	}
-
-	for _, raw := range data.Data.Charts.Rows {
-		var share domain.Share
-		share.CompanyID = companyId
-		if share.Date, err = time.Parse(time.DateOnly, raw.DateTime); err != nil {
-			return
-		}
-		if share.Open, err = toFloat(raw.Open); err != nil {
-			return
-		}
-		if share.High, err = toFloat(raw.High); err != nil {
-			return
-		}
-		if share.Low, err = toFloat(raw.Low); err != nil {
-			return
-		}
-		if share.Close, err = toFloat(raw.Close); err != nil {
-			return
-		}
-		if share.Volume, err = toInt(raw.TotalVolume); err != nil {
-			return
-		}
-		if share.Average, err = toFloat(raw.Average); err != nil {
-			return
+func (s scraper) getShare(ctx context.Context, companyId xid.ID, externalId string, from time.Time, to time.Time, yield func(*domain.Share, error) bool) (err error) {
+	for share, err := range s.priceFeed.GetShares(ctx, companyId, externalId, from, to) {
+		if err != nil {
+			return err
 		}
 
-		if !yield(&share, nil) {
-			return
+		if !yield(share, nil) {
+			return nil
 		}
 	}
 
@@ -0,0 +1,52 @@
+package domain
+
+import "github.com/rs/xid"
+
+// What companies are grouped by in aggregates, in addition to the fiscal year.
+type AggregateGroup string
+
+const (
+	AggregateGroupSector      AggregateGroup = "sector"
+	AggregateGroupCountry     AggregateGroup = "country"
+	AggregateGroupMarketPlace AggregateGroup = "marketplace"
+)
+
+// How the most extreme values of each group are handled, so that a few of them don't dominate the
+// mean.
+type OutlierPolicy string
+
+const (
+	OutlierPolicyWinsorize OutlierPolicy = "winsorize" // Clamp the values in each tail to the tail's percentile
+	OutlierPolicyTrim      OutlierPolicy = "trim"      // Leave out the values in each tail
+	OutlierPolicyNone      OutlierPolicy = "none"
+)
+
+// Statistics of a derived metric over the companies of a group and fiscal year. Companies without
+// a value are left out of every statistic. Monetary values are in millions of the base currency.
+type Aggregate struct {
+	FiscalYear   int               `json:"fiscalYear"`
+	Group        string            `json:"group"` // Sector ID, country code or marketplace code, empty for companies without a sector
+	Name         string            `json:"name"`
+	Count        int               `json:"count"`    // Values that the statistics are computed from
+	Nulls        int               `json:"nulls"`    // Companies without a value
+	Outliers     int               `json:"outliers"` // Values that were winsorized or trimmed
+	Mean         Nullable[float64] `json:"mean"`
+	Median       Nullable[float64] `json:"median"`
+	P25          Nullable[float64] `json:"p25"`
+	P75          Nullable[float64] `json:"p75"`
+	WeightedMean Nullable[float64] `json:"weightedMean"` // Weighted by market cap, over companies with one
+}
+
+type AggregateFilter struct {
+	Metric         MetricID          `query:"metric" default:"evebit"` // A derived metric
+	GroupBy        AggregateGroup    `query:"groupBy" enum:"sector,country,marketplace" default:"sector"`
+	FromYear       int               `query:"fromYear"` // Optional, inclusive
+	ToYear         int               `query:"toYear"`   // Optional, inclusive
+	Outliers       OutlierPolicy     `query:"outliers" enum:"winsorize,trim,none" default:"winsorize"`
+	OutlierPercent int               `query:"outlierPercent" min:"0" max:"25" default:"5"` // Of each tail
+	Limit          int               `query:"limit" min:"1" max:"500" default:"100"`
+	Offset         int               `query:"offset" min:"0"`
+	Sectors        []xid.ID          `query:"sectors"`
+	Countries      []CountryCode     `query:"countries" enum:"se,dk,fi,is"`
+	MarketPlaces   []MarketPlaceCode `query:"marketPlaces" enum:"xsto,xcse,xhel,xice"`
+}
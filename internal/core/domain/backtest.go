@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/webmafia/papi/errors"
+)
+
+var ErrInvalidBacktest = errors.NewFrozenError("INVALID_BACKTEST", "Invalid backtest")
+
+type BacktestRebalance string
+
+const (
+	BacktestRebalanceYearly     BacktestRebalance = "yearly"
+	BacktestRebalanceSemiAnnual BacktestRebalance = "semiannual"
+	BacktestRebalanceQuarterly  BacktestRebalance = "quarterly"
+)
+
+// Months between rebalances.
+func (r BacktestRebalance) Months() int {
+	switch r {
+	case BacktestRebalanceSemiAnnual:
+		return 6
+	case BacktestRebalanceQuarterly:
+		return 3
+	}
+
+	return 12
+}
+
+type BacktestWeighting string
+
+const (
+	BacktestWeightingEqual BacktestWeighting = "equal"
+	BacktestWeightingRank  BacktestWeighting = "rank" // Linearly decreasing with the rank, so the first holding weighs the most
+)
+
+// Annual reports are assumed to be available this many months after the end of the fiscal year,
+// so a rebalance never selects companies by figures that weren't public yet.
+const BacktestReportingLag = 3
+
+// A strategy to simulate. Companies are selected by the filter, in its order, or by a composite
+// ranking within the filter's universe. The first rebalance is at the start of StartYear plus the
+// reporting lag.
+type Backtest struct {
+	Filter    ScreenerFilter    `json:"filter"`
+	Ranking   xid.ID            `json:"ranking"` // Optional, overrides the order of the filter
+	StartYear int               `json:"startYear"`
+	EndYear   int               `json:"endYear"` // Optional, defaults to the last year with prices
+	Holdings  int               `json:"holdings" min:"1" max:"100" default:"30"`
+	Rebalance BacktestRebalance `json:"rebalance" enum:"yearly,semiannual,quarterly" default:"yearly"`
+	Weighting BacktestWeighting `json:"weighting" enum:"equal,rank" default:"equal"`
+}
+
+// The fiscal year whose annual reports are the latest available at a date.
+func BacktestFiscalYear(date time.Time) int {
+	return date.AddDate(0, -BacktestReportingLag, 0).Year() - 1
+}
+
+type BacktestResult struct {
+	Rebalances []BacktestRebalancing `json:"rebalances"`
+	Curve      []BacktestPoint       `json:"curve"`
+	Portfolio  BacktestStats         `json:"portfolio"`
+	Benchmark  BacktestStats         `json:"benchmark"` // All companies in the universe, equally weighted
+}
+
+// The holdings selected at a rebalance, and how they performed until the next one.
+type BacktestRebalancing struct {
+	Date       time.Time         `json:"date"`
+	FiscalYear int               `json:"fiscalYear"`
+	Holdings   []BacktestHolding `json:"holdings"`
+	Return     float64           `json:"return"`
+	Benchmark  float64           `json:"benchmark"`
+}
+
+type BacktestHolding struct {
+	Company IDAndName `json:"company"`
+	Weight  float64   `json:"weight"`
+	Return  float64   `json:"return"`
+}
+
+// The value of the portfolio and the benchmark at the end of a month, starting at 1.
+type BacktestPoint struct {
+	Date      time.Time `json:"date"`
+	Value     float64   `json:"value"`
+	Benchmark float64   `json:"benchmark"`
+}
+
+type BacktestStats struct {
+	Return      float64 `json:"return"`      // Total return over the whole period
+	CAGR        float64 `json:"cagr"`        // Compound annual growth rate
+	Volatility  float64 `json:"volatility"`  // Annualized standard deviation of the monthly returns
+	MaxDrawdown float64 `json:"maxDrawdown"` // Largest fall from a peak, as a positive fraction
+}
+
+// The latest closing price of a company on or before a date.
+type ClosingPrice struct {
+	CompanyID   xid.ID    `json:"companyId"`
+	Date        time.Time `json:"date"`
+	PriceDate   time.Time `json:"priceDate"`
+	Close       float64   `json:"close"`
+	TotalReturn float64   `json:"totalReturn"` // The close with dividends reinvested, see TotalReturn
+}
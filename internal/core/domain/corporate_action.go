@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/webmafia/papi/errors"
+)
+
+var ErrInvalidCorporateAction = errors.NewFrozenError("INVALID_CORPORATE_ACTION", "Invalid corporate action")
+
+type CorporateActionKind string
+
+const (
+	CorporateActionSplit        CorporateActionKind = "split"
+	CorporateActionReverseSplit CorporateActionKind = "reverse_split"
+	CorporateActionBonusIssue   CorporateActionKind = "bonus_issue"
+	CorporateActionSpinOff      CorporateActionKind = "spin_off"
+)
+
+// An action that changes the number of shares or the price of a company without changing its value,
+// so that earlier prices must be adjusted to be comparable with later ones.
+//
+// Ratio is what earlier prices are divided by: new shares per old share for splits (e.g. 2 for a 2:1
+// split), reverse splits (e.g. 0.1 for a 1:10 reverse split) and bonus issues (e.g. 1.1 for one bonus
+// share per ten held), and the price before divided by the price after the spun off shares are
+// removed for spin-offs. Earlier dividends are divided by it as well, and earlier volumes multiplied
+// by it except for spin-offs. Share counts in financials are always the current ones, so they're left
+// as they are.
+type CorporateAction struct {
+	CompanyID xid.ID              `json:"companyId"`
+	Date      time.Time           `json:"date"` // The first trading day after the action
+	Kind      CorporateActionKind `json:"kind" enum:"split,reverse_split,bonus_issue,spin_off"`
+	Ratio     float64             `json:"ratio"`
+	Note      string              `json:"note"`
+}
+
+// Validates that the ratio goes the right way for the kind of action.
+func (a CorporateAction) Validate() error {
+	if a.Date.IsZero() {
+		return ErrInvalidCorporateAction.Detailed("missing date", "date")
+	}
+
+	switch a.Kind {
+	case CorporateActionSplit, CorporateActionBonusIssue, CorporateActionSpinOff:
+		if a.Ratio <= 1 {
+			return ErrInvalidCorporateAction.Detailed("ratio of a "+string(a.Kind)+" must be above 1", "ratio")
+		}
+
+	case CorporateActionReverseSplit:
+		if a.Ratio <= 0 || a.Ratio >= 1 {
+			return ErrInvalidCorporateAction.Detailed("ratio of a reverse_split must be between 0 and 1", "ratio")
+		}
+
+	default:
+		return ErrInvalidCorporateAction.Detailed("unknown kind \""+string(a.Kind)+"\"", "kind")
+	}
+
+	return nil
+}
+
+type CorporateActionFilter struct {
+	Limit   int      `query:"limit" min:"1" max:"500" default:"50"`
+	Offset  int      `query:"offset" min:"0"`
+	Include []xid.ID `query:"include"`
+}
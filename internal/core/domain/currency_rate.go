@@ -1,14 +1,185 @@
 package domain
 
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/rs/xid"
+)
+
 const BaseCurrency = "USD"
 
+// How monetary values are converted between currencies.
+type FXPolicy string
+
+const (
+	FXPolicyStandard FXPolicy = "standard" // Annual average rate for income and cash-flow items, and closing rate for balance-sheet items
+	FXPolicyAverage  FXPolicy = "average"  // Annual average rate for all items
+	FXPolicyClosing  FXPolicy = "closing"  // Closing rate of the fiscal year for all items
+)
+
+// The rates of a currency per unit of the base currency in a quarter (0-3) of a fiscal year.
 type CurrencyRate struct {
 	FiscalYear int
 	Quarter    int
 	Currency   IDAndName
-	Rate       float32
+	Rate       float32 // Average of the daily rates
+	Closing    float32 // Rate on the last day with a rate
+	Days       int     // Number of daily rates
+}
+
+// The rates of a currency per unit of the base currency for a whole fiscal year.
+type AnnualCurrencyRate struct {
+	FiscalYear int
+	CurrencyID xid.ID
+	Average    float32           // Average of the daily rates, weighting each quarter by its days
+	Closing    Nullable[float32] // Closing rate of the last quarter with rates, i.e. the fourth once the year has ended
 }
 
 type CurrencyRateResponse struct {
 	Rates map[string]map[string]float32 `json:"rates"`
 }
+
+// The last moment of each quarter of a fiscal year that daily rates are counted to.
+func QuarterEnds(year int) [4]time.Time {
+	return [4]time.Time{
+		time.Date(year, time.March, 31, 23, 0, 0, 0, time.UTC),
+		time.Date(year, time.June, 30, 23, 0, 0, 0, time.UTC),
+		time.Date(year, time.September, 30, 23, 0, 0, 0, time.UTC),
+		time.Date(year, time.December, 31, 23, 0, 0, 0, time.UTC),
+	}
+}
+
+// Groups daily rates of a fiscal year, keyed by date and currency symbol, into quarterly rates of
+// the currencies. Quarters without any daily rates, e.g. those that haven't started yet, are left out.
+func QuarterlyCurrencyRates(year int, currencies []*IDAndName, daily map[string]map[string]float32) ([]CurrencyRate, error) {
+	type quarter struct {
+		sum     float32
+		count   int
+		last    time.Time
+		closing float32
+	}
+
+	ends := QuarterEnds(year)
+	quarters := make(map[string]*[4]quarter, len(currencies))
+
+	for _, c := range currencies {
+		quarters[c.Name] = &[4]quarter{}
+	}
+
+	for ts, r := range daily {
+		t, err := time.Parse(time.DateOnly, ts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for symbol, rate := range r {
+			q, ok := quarters[symbol]
+
+			if !ok {
+				continue
+			}
+
+			for i, end := range ends {
+				if t.After(end) {
+					continue
+				}
+
+				q[i].count++
+				q[i].sum += rate
+
+				if t.After(q[i].last) {
+					q[i].last = t
+					q[i].closing = rate
+				}
+
+				break
+			}
+		}
+	}
+
+	rates := make([]CurrencyRate, 0, len(currencies)*4)
+
+	for _, c := range currencies {
+		for i, q := range quarters[c.Name] {
+			if q.count == 0 {
+				continue
+			}
+
+			rates = append(rates, CurrencyRate{
+				FiscalYear: year,
+				Quarter:    i,
+				Currency:   *c,
+				Rate:       q.sum / float32(q.count),
+				Closing:    q.closing,
+				Days:       q.count,
+			})
+		}
+	}
+
+	return rates, nil
+}
+
+// Aggregates quarterly rates into the annual rates of each currency and fiscal year, ordered by
+// fiscal year. Quarters stored before their days were counted are weighted equally, and a missing
+// closing rate is stored as 0.
+func AnnualCurrencyRates(quarters []CurrencyRate) []AnnualCurrencyRate {
+	type key struct {
+		year       int
+		currencyId xid.ID
+	}
+
+	type annual struct {
+		weighted, sum float64
+		days, count   int
+		lastQuarter   int
+		closing       float32
+	}
+
+	sums := make(map[key]*annual)
+
+	for _, q := range quarters {
+		k := key{q.FiscalYear, q.Currency.ID}
+		a, ok := sums[k]
+
+		if !ok {
+			a = &annual{lastQuarter: -1}
+			sums[k] = a
+		}
+
+		a.weighted += float64(q.Rate) * float64(q.Days)
+		a.days += q.Days
+		a.sum += float64(q.Rate)
+		a.count++
+
+		if q.Quarter > a.lastQuarter {
+			a.lastQuarter = q.Quarter
+			a.closing = q.Closing
+		}
+	}
+
+	rates := make([]AnnualCurrencyRate, 0, len(sums))
+
+	for k, a := range sums {
+		rate := AnnualCurrencyRate{
+			FiscalYear: k.year,
+			CurrencyID: k.currencyId,
+			Average:    float32(a.sum / float64(a.count)),
+			Closing:    Nullable[float32]{Content: a.closing, Valid: a.closing != 0},
+		}
+
+		if a.days > 0 {
+			rate.Average = float32(a.weighted / float64(a.days))
+		}
+
+		rates = append(rates, rate)
+	}
+
+	slices.SortFunc(rates, func(a, b AnnualCurrencyRate) int {
+		return cmp.Or(cmp.Compare(a.FiscalYear, b.FiscalYear), a.CurrencyID.Compare(b.CurrencyID))
+	})
+
+	return rates
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/xid"
+)
+
+func TestQuarterlyCurrencyRates(t *testing.T) {
+	sek := &IDAndName{ID: xid.New(), Name: "SEK"}
+	eur := &IDAndName{ID: xid.New(), Name: "EUR"}
+
+	daily := map[string]map[string]float32{
+		"2024-01-02": {"SEK": 10, "EUR": 0.5, "NOK": 11},
+		"2024-03-28": {"SEK": 12, "EUR": 0.75},
+		"2024-03-31": {"SEK": 14},
+		"2024-04-02": {"SEK": 9},
+		"2024-06-28": {"SEK": 11},
+	}
+
+	got, err := QuarterlyCurrencyRates(2024, []*IDAndName{sek, eur}, daily)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third and fourth quarters haven't any rates and are left out, as is NOK that isn't asked for
+	want := []CurrencyRate{
+		{FiscalYear: 2024, Quarter: 0, Currency: *sek, Rate: 12, Closing: 14, Days: 3},
+		{FiscalYear: 2024, Quarter: 1, Currency: *sek, Rate: 10, Closing: 11, Days: 2},
+		{FiscalYear: 2024, Quarter: 0, Currency: *eur, Rate: 0.625, Closing: 0.75, Days: 2},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QuarterlyCurrencyRates() = %+v, want %+v", got, want)
+	}
+}
+
+func TestQuarterlyCurrencyRatesInvalidDate(t *testing.T) {
+	sek := &IDAndName{ID: xid.New(), Name: "SEK"}
+
+	if _, err := QuarterlyCurrencyRates(2024, []*IDAndName{sek}, map[string]map[string]float32{"2024-13-01": {"SEK": 10}}); err == nil {
+		t.Error("QuarterlyCurrencyRates() returned no error for an invalid date")
+	}
+}
+
+func TestAnnualCurrencyRates(t *testing.T) {
+	sek := IDAndName{ID: xid.New(), Name: "SEK"}
+
+	tests := []struct {
+		name     string
+		quarters []CurrencyRate
+		want     AnnualCurrencyRate
+	}{
+		{
+			name: "average weighted by days and closing of the fourth quarter",
+			quarters: []CurrencyRate{
+				{FiscalYear: 2023, Quarter: 3, Currency: sek, Rate: 12, Closing: 13, Days: 60},
+				{FiscalYear: 2023, Quarter: 0, Currency: sek, Rate: 10, Closing: 10.5, Days: 60},
+				{FiscalYear: 2023, Quarter: 1, Currency: sek, Rate: 8, Closing: 8.5, Days: 20},
+				{FiscalYear: 2023, Quarter: 2, Currency: sek, Rate: 10, Closing: 9, Days: 60},
+			},
+			want: AnnualCurrencyRate{
+				FiscalYear: 2023,
+				CurrencyID: sek.ID,
+				Average:    10.4, // (10*60 + 8*20 + 10*60 + 12*60) / 200
+				Closing:    Nullable[float32]{Content: 13, Valid: true},
+			},
+		},
+		{
+			name: "closing of the last quarter with rates in a year that hasn't ended",
+			quarters: []CurrencyRate{
+				{FiscalYear: 2025, Quarter: 0, Currency: sek, Rate: 10, Closing: 11, Days: 30},
+				{FiscalYear: 2025, Quarter: 1, Currency: sek, Rate: 12, Closing: 12.5, Days: 10},
+			},
+			want: AnnualCurrencyRate{
+				FiscalYear: 2025,
+				CurrencyID: sek.ID,
+				Average:    10.5,
+				Closing:    Nullable[float32]{Content: 12.5, Valid: true},
+			},
+		},
+		{
+			name: "simple average of quarters stored without days or closing rates",
+			quarters: []CurrencyRate{
+				{FiscalYear: 2021, Quarter: 0, Currency: sek, Rate: 8},
+				{FiscalYear: 2021, Quarter: 1, Currency: sek, Rate: 9},
+				{FiscalYear: 2021, Quarter: 2, Currency: sek, Rate: 10},
+				{FiscalYear: 2021, Quarter: 3, Currency: sek, Rate: 13},
+			},
+			want: AnnualCurrencyRate{
+				FiscalYear: 2021,
+				CurrencyID: sek.ID,
+				Average:    10,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AnnualCurrencyRates(tt.quarters)
+
+			if want := []AnnualCurrencyRate{tt.want}; !reflect.DeepEqual(got, want) {
+				t.Errorf("AnnualCurrencyRates() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestAnnualCurrencyRatesGroups(t *testing.T) {
+	sek := IDAndName{ID: xid.New(), Name: "SEK"}
+	eur := IDAndName{ID: xid.New(), Name: "EUR"}
+
+	got := AnnualCurrencyRates([]CurrencyRate{
+		{FiscalYear: 2024, Quarter: 3, Currency: eur, Rate: 0.9, Closing: 0.9, Days: 60},
+		{FiscalYear: 2023, Quarter: 3, Currency: sek, Rate: 11, Closing: 11, Days: 60},
+		{FiscalYear: 2024, Quarter: 3, Currency: sek, Rate: 10, Closing: 10, Days: 60},
+	})
+
+	if len(got) != 3 {
+		t.Fatalf("AnnualCurrencyRates() = %+v, want a rate per currency and fiscal year", got)
+	}
+
+	for i, r := range got {
+		if i > 0 && r.FiscalYear < got[i-1].FiscalYear {
+			t.Errorf("AnnualCurrencyRates() = %+v, want them ordered by fiscal year", got)
+		}
+	}
+
+	if got[0].FiscalYear != 2023 || got[0].CurrencyID != sek.ID || got[0].Average != 11 {
+		t.Errorf("AnnualCurrencyRates()[0] = %+v, want SEK in 2023", got[0])
+	}
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/webmafia/papi/errors"
+)
+
+var ErrUnknownCurrency = errors.NewFrozenError("UNKNOWN_CURRENCY", "Unknown currency")
+
+// A dividend paid per share, in the currency it was declared in. The currency is looked up by its
+// name when it's created without an ID, e.g. {"name": "SEK"}.
+type Dividend struct {
+	CompanyID xid.ID    `json:"companyId"`
+	ExDate    time.Time `json:"exDate"`
+	Amount    float64   `json:"amount"`
+	Currency  IDAndName `json:"currency"`
+}
+
+type DividendFilter struct {
+	Limit   int      `query:"limit" min:"1" max:"500" default:"50"`
+	Offset  int      `query:"offset" min:"0"`
+	Include []xid.ID `query:"include"`
+}
+
+// A closing price with the dividends that went ex since the previous close. TotalReturn is the
+// first close of the company, grown by the price changes with all dividends reinvested.
+type TotalReturn struct {
+	Date        time.Time `json:"date"`
+	Close       float64   `json:"close"`
+	Dividend    float64   `json:"dividend"`
+	TotalReturn float64   `json:"totalReturn"`
+}
+
+type TotalReturnFilter struct {
+	Include []xid.ID `query:"include"`
+	Limit   int      `query:"limit" min:"1" max:"5000" default:"1000"`
+	Offset  int      `query:"offset" min:"0"`
+}
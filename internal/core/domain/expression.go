@@ -0,0 +1,444 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/webmafia/papi/errors"
+)
+
+var (
+	ErrInvalidExpression = errors.NewFrozenError("INVALID_EXPRESSION", "Invalid filter expression")
+	ErrUnknownMetric     = errors.NewFrozenError("UNKNOWN_METRIC", "Unknown metric")
+)
+
+// A parsed filter expression, e.g. `(roc > 0.2 and evebit < 8) or pe is null`.
+type FilterExpr interface {
+	// Whether the expression evaluates to a condition rather than a number.
+	IsCondition() bool
+}
+
+type ExprOp string
+
+const (
+	ExprOpAnd ExprOp = "and"
+	ExprOpOr  ExprOp = "or"
+	ExprOpEq  ExprOp = "="
+	ExprOpNe  ExprOp = "!="
+	ExprOpLt  ExprOp = "<"
+	ExprOpLte ExprOp = "<="
+	ExprOpGt  ExprOp = ">"
+	ExprOpGte ExprOp = ">="
+	ExprOpAdd ExprOp = "+"
+	ExprOpSub ExprOp = "-"
+	ExprOpMul ExprOp = "*"
+	ExprOpDiv ExprOp = "/"
+)
+
+type ExprBinary struct {
+	Op    ExprOp
+	Left  FilterExpr
+	Right FilterExpr
+}
+
+func (e ExprBinary) IsCondition() bool {
+	switch e.Op {
+	case ExprOpAdd, ExprOpSub, ExprOpMul, ExprOpDiv:
+		return false
+	}
+
+	return true
+}
+
+type ExprNot struct {
+	Expr FilterExpr
+}
+
+func (ExprNot) IsCondition() bool { return true }
+
+type ExprNeg struct {
+	Expr FilterExpr
+}
+
+func (ExprNeg) IsCondition() bool { return false }
+
+type ExprIsNull struct {
+	Expr FilterExpr
+	Not  bool
+}
+
+func (ExprIsNull) IsCondition() bool { return true }
+
+type ExprMetric struct {
+	Name string
+}
+
+func (ExprMetric) IsCondition() bool { return false }
+
+type ExprNumber struct {
+	Value float64
+}
+
+func (ExprNumber) IsCondition() bool { return false }
+
+// Calls fn for every metric referenced in the expression.
+func WalkMetrics(e FilterExpr, fn func(name string)) {
+	switch v := e.(type) {
+	case ExprBinary:
+		WalkMetrics(v.Left, fn)
+		WalkMetrics(v.Right, fn)
+	case ExprNot:
+		WalkMetrics(v.Expr, fn)
+	case ExprNeg:
+		WalkMetrics(v.Expr, fn)
+	case ExprIsNull:
+		WalkMetrics(v.Expr, fn)
+	case ExprMetric:
+		fn(v.Name)
+	}
+}
+
+// Parses a filter expression. An empty string results in a nil expression.
+//
+// Grammar, from lowest to highest precedence:
+//
+//	or      = and { "or" and }
+//	and     = not { "and" not }
+//	not     = "not" not | compare
+//	compare = sum [ ( "=" | "!=" | "<>" | "<" | "<=" | ">" | ">=" ) sum | "is" [ "not" ] "null" ]
+//	sum     = product { ( "+" | "-" ) product }
+//	product = unary { ( "*" | "/" ) unary }
+//	unary   = "-" unary | number | metric | "(" or ")"
+func ParseFilterExpression(s string) (e FilterExpr, err error) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+
+	p := exprParser{src: s}
+
+	if err = p.tokenize(); err != nil {
+		return
+	}
+
+	if e, err = p.parseOr(); err != nil {
+		return
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+
+	if !e.IsCondition() {
+		return nil, ErrInvalidExpression.Detailed("expression must be a condition, e.g. `pe < 15`", "expression")
+	}
+
+	return
+}
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	src    string
+	tokens []token
+	cursor int
+}
+
+func (p *exprParser) tokenize() (err error) {
+	s := p.src
+
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			p.tokens = append(p.tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			p.tokens = append(p.tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: s[i:j], pos: i})
+			i = j
+
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenIdent, text: s[i:j], pos: i})
+			i = j
+
+		case strings.ContainsRune("=!<>+-*/", c):
+			j := i + 1
+			if j < len(s) && (s[j] == '=' || c == '<' && s[j] == '>') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenOp, text: s[i:j], pos: i})
+			i = j
+
+		default:
+			return p.errorf(token{pos: i}, "unexpected character %q", c)
+		}
+	}
+
+	p.tokens = append(p.tokens, token{kind: tokenEOF, text: "end of expression", pos: len(s)})
+
+	return
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.cursor]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.cursor]
+
+	if tok.kind != tokenEOF {
+		p.cursor++
+	}
+
+	return tok
+}
+
+func (p *exprParser) keyword(kw string) bool {
+	if tok := p.peek(); tok.kind == tokenIdent && strings.EqualFold(tok.text, kw) {
+		p.cursor++
+		return true
+	}
+
+	return false
+}
+
+func (p *exprParser) errorf(tok token, format string, args ...any) error {
+	return ErrInvalidExpression.Detailed(fmt.Sprintf(format, args...)+" at position "+strconv.Itoa(tok.pos+1), "expression")
+}
+
+func (p *exprParser) parseOr() (e FilterExpr, err error) {
+	return p.parseLogical(ExprOpOr, p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (e FilterExpr, err error) {
+	return p.parseLogical(ExprOpAnd, p.parseNot)
+}
+
+func (p *exprParser) parseLogical(op ExprOp, operand func() (FilterExpr, error)) (e FilterExpr, err error) {
+	tok := p.peek()
+
+	if e, err = operand(); err != nil {
+		return
+	}
+
+	for {
+		opTok := p.peek()
+
+		if !p.keyword(string(op)) {
+			return
+		}
+
+		if !e.IsCondition() {
+			return nil, p.errorf(tok, "expected a condition before %q", opTok.text)
+		}
+
+		tok = p.peek()
+		var right FilterExpr
+
+		if right, err = operand(); err != nil {
+			return
+		}
+
+		if !right.IsCondition() {
+			return nil, p.errorf(tok, "expected a condition after %q", opTok.text)
+		}
+
+		e = ExprBinary{Op: op, Left: e, Right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (e FilterExpr, err error) {
+	tok := p.peek()
+
+	if !p.keyword("not") {
+		return p.parseCompare()
+	}
+
+	if e, err = p.parseNot(); err != nil {
+		return
+	}
+
+	if !e.IsCondition() {
+		return nil, p.errorf(tok, "expected a condition after \"not\"")
+	}
+
+	return ExprNot{Expr: e}, nil
+}
+
+func (p *exprParser) parseCompare() (e FilterExpr, err error) {
+	if e, err = p.parseSum(); err != nil {
+		return
+	}
+
+	tok := p.peek()
+
+	if p.keyword("is") {
+		not := p.keyword("not")
+
+		if !p.keyword("null") {
+			return nil, p.errorf(p.peek(), "expected \"null\"")
+		}
+
+		return ExprIsNull{Expr: e, Not: not}, nil
+	}
+
+	if tok.kind != tokenOp {
+		return
+	}
+
+	var op ExprOp
+
+	switch tok.text {
+	case "=", "==":
+		op = ExprOpEq
+	case "!=", "<>":
+		op = ExprOpNe
+	case "<":
+		op = ExprOpLt
+	case "<=":
+		op = ExprOpLte
+	case ">":
+		op = ExprOpGt
+	case ">=":
+		op = ExprOpGte
+	default:
+		return
+	}
+
+	p.next()
+
+	if e.IsCondition() {
+		return nil, p.errorf(tok, "cannot compare a condition")
+	}
+
+	right, err := p.parseSum()
+
+	if err != nil {
+		return
+	}
+
+	if right.IsCondition() {
+		return nil, p.errorf(tok, "cannot compare a condition")
+	}
+
+	return ExprBinary{Op: op, Left: e, Right: right}, nil
+}
+
+func (p *exprParser) parseSum() (e FilterExpr, err error) {
+	return p.parseArithmetic(p.parseProduct, ExprOpAdd, ExprOpSub)
+}
+
+func (p *exprParser) parseProduct() (e FilterExpr, err error) {
+	return p.parseArithmetic(p.parseUnary, ExprOpMul, ExprOpDiv)
+}
+
+func (p *exprParser) parseArithmetic(operand func() (FilterExpr, error), ops ...ExprOp) (e FilterExpr, err error) {
+	if e, err = operand(); err != nil {
+		return
+	}
+
+	for {
+		tok := p.peek()
+
+		if tok.kind != tokenOp || (tok.text != string(ops[0]) && tok.text != string(ops[1])) {
+			return
+		}
+
+		p.next()
+		var right FilterExpr
+
+		if right, err = operand(); err != nil {
+			return
+		}
+
+		if e.IsCondition() || right.IsCondition() {
+			return nil, p.errorf(tok, "cannot use a condition in arithmetic")
+		}
+
+		e = ExprBinary{Op: ExprOp(tok.text), Left: e, Right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (e FilterExpr, err error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenOp:
+		if tok.text != "-" {
+			break
+		}
+
+		if e, err = p.parseUnary(); err != nil {
+			return
+		}
+
+		if e.IsCondition() {
+			return nil, p.errorf(tok, "cannot negate a condition")
+		}
+
+		return ExprNeg{Expr: e}, nil
+
+	case tokenNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %q", tok.text)
+		}
+
+		return ExprNumber{Value: v}, nil
+
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "and", "or", "not", "is", "null":
+			return nil, p.errorf(tok, "unexpected %q", tok.text)
+		}
+
+		return ExprMetric{Name: tok.text}, nil
+
+	case tokenLParen:
+		if e, err = p.parseOr(); err != nil {
+			return
+		}
+
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected \")\"")
+		}
+
+		return
+	}
+
+	return nil, p.errorf(tok, "unexpected %q", tok.text)
+}
@@ -0,0 +1,264 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/webmafia/papi/errors"
+)
+
+func metric(name string) ExprMetric { return ExprMetric{Name: name} }
+
+func number(v float64) ExprNumber { return ExprNumber{Value: v} }
+
+func binary(op ExprOp, left, right FilterExpr) ExprBinary {
+	return ExprBinary{Op: op, Left: left, Right: right}
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want FilterExpr
+	}{
+		{
+			name: "empty",
+			src:  "  ",
+			want: nil,
+		},
+		{
+			name: "comparison",
+			src:  "pe < 15",
+			want: binary(ExprOpLt, metric("pe"), number(15)),
+		},
+		{
+			name: "and binds tighter than or",
+			src:  "pe < 10 or roc > 0.2 and evebit < 8",
+			want: binary(ExprOpOr,
+				binary(ExprOpLt, metric("pe"), number(10)),
+				binary(ExprOpAnd,
+					binary(ExprOpGt, metric("roc"), number(0.2)),
+					binary(ExprOpLt, metric("evebit"), number(8)),
+				),
+			),
+		},
+		{
+			name: "parentheses",
+			src:  "(pe < 10 or roc > 0.2) and evebit < 8",
+			want: binary(ExprOpAnd,
+				binary(ExprOpOr,
+					binary(ExprOpLt, metric("pe"), number(10)),
+					binary(ExprOpGt, metric("roc"), number(0.2)),
+				),
+				binary(ExprOpLt, metric("evebit"), number(8)),
+			),
+		},
+		{
+			name: "not binds tighter than and",
+			src:  "not pe < 10 and roc > 0",
+			want: binary(ExprOpAnd,
+				ExprNot{Expr: binary(ExprOpLt, metric("pe"), number(10))},
+				binary(ExprOpGt, metric("roc"), number(0)),
+			),
+		},
+		{
+			name: "product binds tighter than sum",
+			src:  "roc * 2 + 1 >= pe / 3",
+			want: binary(ExprOpGte,
+				binary(ExprOpAdd, binary(ExprOpMul, metric("roc"), number(2)), number(1)),
+				binary(ExprOpDiv, metric("pe"), number(3)),
+			),
+		},
+		{
+			name: "arithmetic is left associative",
+			src:  "1 - 2 - 3 <= -pe",
+			want: binary(ExprOpLte,
+				binary(ExprOpSub, binary(ExprOpSub, number(1), number(2)), number(3)),
+				ExprNeg{Expr: metric("pe")},
+			),
+		},
+		{
+			name: "is null",
+			src:  "pe is null",
+			want: ExprIsNull{Expr: metric("pe")},
+		},
+		{
+			name: "is not null, case insensitive",
+			src:  "pe IS NOT NULL Or roc Is Null",
+			want: binary(ExprOpOr,
+				ExprIsNull{Expr: metric("pe"), Not: true},
+				ExprIsNull{Expr: metric("roc")},
+			),
+		},
+		{
+			name: "not equal",
+			src:  "pe <> 1 and pe != 2",
+			want: binary(ExprOpAnd,
+				binary(ExprOpNe, metric("pe"), number(1)),
+				binary(ExprOpNe, metric("pe"), number(2)),
+			),
+		},
+		{
+			name: "equal",
+			src:  "pe == 1 or pe = 2",
+			want: binary(ExprOpOr,
+				binary(ExprOpEq, metric("pe"), number(1)),
+				binary(ExprOpEq, metric("pe"), number(2)),
+			),
+		},
+		{
+			name: "metric names with digits and underscores",
+			src:  "revenue_cagr_5y > .1",
+			want: binary(ExprOpGt, metric("revenue_cagr_5y"), number(0.1)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilterExpression(tt.src)
+
+			if err != nil {
+				t.Fatalf("ParseFilterExpression(%q) returned error: %v", tt.src, details(err))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilterExpression(%q) = %#v, want %#v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		details string
+	}{
+		{
+			name:    "not a condition",
+			src:     "pe + 1",
+			details: "expression must be a condition",
+		},
+		{
+			name:    "unexpected character",
+			src:     "pe $ 1",
+			details: `unexpected character '$' at position 4`,
+		},
+		{
+			name:    "missing operand",
+			src:     "pe <",
+			details: `unexpected "end of expression" at position 5`,
+		},
+		{
+			name:    "missing condition after and",
+			src:     "pe < 1 and",
+			details: `unexpected "end of expression" at position 11`,
+		},
+		{
+			name:    "number before and",
+			src:     "pe and roc < 1",
+			details: `expected a condition before "and" at position 1`,
+		},
+		{
+			name:    "number after or",
+			src:     "pe < 1 or roc",
+			details: `expected a condition after "or" at position 11`,
+		},
+		{
+			name:    "unclosed parenthesis",
+			src:     "(pe < 1",
+			details: `expected ")" at position 8`,
+		},
+		{
+			name:    "is without null",
+			src:     "pe is 1",
+			details: `expected "null" at position 7`,
+		},
+		{
+			name:    "chained comparison",
+			src:     "1 < pe < 2",
+			details: `unexpected "<" at position 8`,
+		},
+		{
+			name:    "comparing a condition",
+			src:     "(pe < 1) = 1",
+			details: `cannot compare a condition at position 10`,
+		},
+		{
+			name:    "condition in arithmetic",
+			src:     "pe + (roc > 1) > 0",
+			details: `cannot use a condition in arithmetic at position 4`,
+		},
+		{
+			name:    "negated condition",
+			src:     "-(pe < 1)",
+			details: `cannot negate a condition at position 1`,
+		},
+		{
+			name:    "not of a number",
+			src:     "not pe",
+			details: `expected a condition after "not" at position 1`,
+		},
+		{
+			name:    "invalid number",
+			src:     "pe > 1.2.3",
+			details: `invalid number "1.2.3" at position 6`,
+		},
+		{
+			name:    "keyword as metric",
+			src:     "null > 1",
+			details: `unexpected "null" at position 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilterExpression(tt.src)
+
+			if err == nil {
+				t.Fatalf("ParseFilterExpression(%q) = %#v, want error", tt.src, got)
+			}
+
+			e, ok := err.(errors.Error)
+
+			if !ok {
+				t.Fatalf("ParseFilterExpression(%q) returned %T, want errors.Error", tt.src, err)
+			}
+
+			if e.Code() != "INVALID_EXPRESSION" {
+				t.Errorf("ParseFilterExpression(%q) code = %q, want INVALID_EXPRESSION", tt.src, e.Code())
+			}
+
+			if !strings.Contains(e.Details(), tt.details) {
+				t.Errorf("ParseFilterExpression(%q) details = %q, want %q", tt.src, e.Details(), tt.details)
+			}
+		})
+	}
+}
+
+func TestWalkMetrics(t *testing.T) {
+	expr, err := ParseFilterExpression("not (pe < roc * 2) and -evebit > 1 or fcf is null")
+
+	if err != nil {
+		t.Fatal(details(err))
+	}
+
+	var got []string
+
+	WalkMetrics(expr, func(name string) {
+		got = append(got, name)
+	})
+
+	if want := []string{"pe", "roc", "evebit", "fcf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkMetrics() = %v, want %v", got, want)
+	}
+}
+
+func details(err error) string {
+	if e, ok := err.(errors.Error); ok {
+		return e.Details()
+	}
+
+	return err.Error()
+}
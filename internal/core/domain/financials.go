@@ -3,18 +3,62 @@ package domain
 import (
 	"github.com/rs/xid"
 	"github.com/webmafia/papi"
+	"github.com/webmafia/papi/errors"
 )
 
+var ErrInvalidFinancials = errors.NewFrozenError("INVALID_FINANCIALS", "Invalid financials")
+
 const (
 	TaxRate = 20.6
 )
 
+// A reporting period within a fiscal year.
+type FinancialPeriod string
+
+const (
+	FinancialPeriodYear FinancialPeriod = "fy"
+	FinancialPeriodQ1   FinancialPeriod = "q1"
+	FinancialPeriodQ2   FinancialPeriod = "q2"
+	FinancialPeriodQ3   FinancialPeriod = "q3"
+	FinancialPeriodQ4   FinancialPeriod = "q4"
+	FinancialPeriodH1   FinancialPeriod = "h1"
+	FinancialPeriodH2   FinancialPeriod = "h2"
+	FinancialPeriodTTM  FinancialPeriod = "ttm" // Trailing twelve months
+)
+
+// Whether the period is a quarter or half-year.
+func (p FinancialPeriod) Interim() bool {
+	switch p {
+	case FinancialPeriodQ1, FinancialPeriodQ2, FinancialPeriodQ3, FinancialPeriodQ4, FinancialPeriodH1, FinancialPeriodH2:
+		return true
+	}
+
+	return false
+}
+
+// Which financials are used: those of whole fiscal years, of quarters and half-years, or of the
+// trailing twelve months up until each company's latest quarter or half-year.
+type PeriodKind string
+
+const (
+	PeriodKindAnnual  PeriodKind = "annual"
+	PeriodKindInterim PeriodKind = "interim"
+	PeriodKindTTM     PeriodKind = "ttm"
+)
+
 type Financials struct {
 	CompanyID   xid.ID               `json:"companyId"`
 	FiscalYear  int                  `json:"fiscalYear"`
+	Period      FinancialPeriod      `json:"period" enum:"fy,q1,q2,q3,q4,h1,h2,ttm"`
+	Through     FinancialPeriod      `json:"through,omitempty"` // For the trailing twelve months, the latest period included
 	CurrencyID  xid.ID               `json:"currency"`
 	StaticData  FinancialData        `json:"staticData"`
 	DerivedData DerivedFinancialData `json:"derivedData"`
+
+	// Display currency, when monetary values are converted from the reporting currency
+	DisplayCurrency     string            `json:"displayCurrency,omitempty"`
+	ExchangeRate        Nullable[float64] `json:"exchangeRate,omitzero"`        // Units of the display currency per unit of the reporting currency, for income and cash-flow items
+	BalanceExchangeRate Nullable[float64] `json:"balanceExchangeRate,omitzero"` // Same as ExchangeRate, but for balance-sheet items
 }
 
 type FinancialData struct {
@@ -35,6 +79,32 @@ type FinancialData struct {
 	ShortTermInvestments int `json:"short_term_investments"`
 	TotalAssets          int `json:"total_assets"`
 	TotalLiabilities     int `json:"total_liabilities"`
+
+	// Line items that aren't reported by every source, and are null rather than zero when missing
+	CurrentAssets      Nullable[int64] `json:"current_assets"`
+	CurrentLiabilities Nullable[int64] `json:"current_liabilities"`
+	RetainedEarnings   Nullable[int64] `json:"retained_earnings"`
+	Receivables        Nullable[int64] `json:"receivables"`
+	Depreciation       Nullable[int64] `json:"depreciation"`
+	SGA                Nullable[int64] `json:"sga"` // Selling, general and administrative expenses
+}
+
+// Validates that the financials are of a whole fiscal year or an interim period, as the trailing
+// twelve months are computed from the latter.
+func (f Financials) Validate() error {
+	if f.FiscalYear <= 0 {
+		return ErrInvalidFinancials.Detailed("missing fiscal year", "fiscalYear")
+	}
+
+	if f.CurrencyID.IsNil() {
+		return ErrInvalidFinancials.Detailed("missing currency", "currency")
+	}
+
+	if f.Period != "" && f.Period != FinancialPeriodYear && !f.Period.Interim() {
+		return ErrInvalidFinancials.Detailed("period must be fy or a quarter or half-year", "period")
+	}
+
+	return nil
 }
 
 // Derived financials
@@ -52,15 +122,23 @@ type DerivedFinancialData struct {
 	DebtToEbit          Nullable[float64] `json:"debt_to_ebit"`
 	DebtToAssets        Nullable[float64] `json:"debt_to_assets"`
 	CashConversion      Nullable[float64] `json:"cash_conversion"`
+	MarketCap           Nullable[int64]   `json:"market_cap"`
+	EnterpriseValue     Nullable[int64]   `json:"enterprise_value"`
 }
 
 type FinancialFilter struct {
-	Order   string   `query:"order" enum:"asc,desc" default:"asc"`
-	OrderBy string   `query:"orderBy" enum:"name" default:"name"`
-	Limit   int      `query:"limit" min:"1" max:"500" default:"50"`
-	Offset  int      `query:"offset" min:"0"`
-	Include []xid.ID `query:"include"`
-	Search  string   `query:"search"`
+	Order   string     `query:"order" enum:"asc,desc" default:"asc"`
+	OrderBy string     `query:"orderBy" enum:"name" default:"name"`
+	Limit   int        `query:"limit" min:"1" max:"500" default:"50"`
+	Offset  int        `query:"offset" min:"0"`
+	Include []xid.ID   `query:"include"`
+	Search  string     `query:"search"`
+	Period  PeriodKind `query:"period" enum:"annual,interim,ttm" default:"annual"`
+
+	// Converts monetary values to this currency. Values are kept in the reporting currency for
+	// fiscal years without exchange rates.
+	Currency string   `query:"currency" enum:"SEK,EUR,DKK,ISK,USD"`
+	FXPolicy FXPolicy `query:"fxPolicy" enum:"standard,average,closing" default:"standard"`
 }
 
 var _ papi.FileType = FinancialsFile{}
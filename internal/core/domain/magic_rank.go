@@ -14,10 +14,29 @@ type MagicRank struct {
 
 type MagicRankFilter struct {
 	Order      string   `query:"order" enum:"asc,desc" default:"asc"`
-	OrderBy    string   `query:"orderBy" enum:"rank" default:"rank"`
+	OrderBy    string   `query:"orderBy" enum:"rank,roc,earningsYield,name" default:"rank"`
 	Limit      int      `query:"limit" min:"1" max:"500" default:"50"`
 	Offset     int      `query:"offset" min:"0"`
 	Include    []xid.ID `query:"include"`
 	Search     string   `query:"search"`
 	FiscalYear int      `query:"fiscalYear"`
+
+	// Variants of the ranking, that change which companies are ranked against each other
+	ExcludeFinancials  bool     `query:"excludeFinancials"`            // Leave out banks, insurance and other financials
+	ExcludeUtilities   bool     `query:"excludeUtilities"`             // Leave out utilities
+	MinMarketCap       int      `query:"minMarketCap" min:"0"`         // In millions of the base currency
+	MaxMarketCap       int      `query:"maxMarketCap" min:"0"`         // In millions of the base currency
+	MinEnterpriseValue int      `query:"minEnterpriseValue" min:"0"`   // In millions of the base currency
+	MaxEnterpriseValue int      `query:"maxEnterpriseValue" min:"0"`   // In millions of the base currency
+	Countries          []string `query:"countries" enum:"se,dk,fi,is"` // Only rank companies in these countries
+	PerCountry         bool     `query:"perCountry"`                   // Rank each country separately
+	Top                int      `query:"top" min:"0" max:"1000"`       // Only the top ranks, per country if ranked per country
 }
+
+// Sector names, matched case-insensitively, that are left out of the magic formula on request.
+// Greenblatt leaves them out as their balance sheets make return on capital and earnings yield
+// misleading.
+var (
+	FinancialSectorPatterns = []string{"%financ%", "%finans%", "%bank%", "%insurance%", "%försäkring%"}
+	UtilitySectorPatterns   = []string{"%utilit%", "%kraftförsörjning%", "%allmännyttig%"}
+)
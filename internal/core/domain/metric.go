@@ -0,0 +1,398 @@
+package domain
+
+import (
+	"encoding/json"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/rs/xid"
+	"github.com/webmafia/papi/openapi"
+	"github.com/webmafia/papi/registry"
+)
+
+type MetricID string
+
+// The table (or view) a metric is selected from. Each source has a fixed alias that metric
+// expressions are written against.
+type MetricSource string
+
+const (
+	MetricSourceCompanies            MetricSource = "companies"
+	MetricSourceCurrencies           MetricSource = "currencies"
+	MetricSourceSectors              MetricSource = "sectors"
+	MetricSourceFinancials           MetricSource = "financials"
+	MetricSourceDerivedFinancials    MetricSource = "derived_financials"
+	MetricSourceMagicFormulaRankings MetricSource = "magic_formula_rankings"
+	MetricSourceDividendMetrics      MetricSource = "dividend_metrics"
+	MetricSourceFinancialScores      MetricSource = "financial_scores"
+	MetricSourcePriceMetrics         MetricSource = "price_metrics"
+)
+
+func (s MetricSource) Alias() string {
+	switch s {
+	case MetricSourceCompanies:
+		return "c"
+	case MetricSourceCurrencies:
+		return "curr"
+	case MetricSourceSectors:
+		return "sec"
+	case MetricSourceFinancials:
+		return "f"
+	case MetricSourceDerivedFinancials:
+		return "df"
+	case MetricSourceMagicFormulaRankings:
+		return "m"
+	case MetricSourceDividendMetrics:
+		return "dm"
+	case MetricSourceFinancialScores:
+		return "fs"
+	case MetricSourcePriceMetrics:
+		return "pm"
+	}
+
+	return string(s)
+}
+
+type MetricUnit string
+
+const (
+	MetricUnitText    MetricUnit = "text"
+	MetricUnitMoney   MetricUnit = "money"   // Millions in the reporting currency
+	MetricUnitRatio   MetricUnit = "ratio"   // A multiple, e.g. P/E
+	MetricUnitPercent MetricUnit = "percent" // A fraction, e.g. 0.15 for 15%
+	MetricUnitCount   MetricUnit = "count"
+)
+
+type Metric struct {
+	ID          MetricID     `json:"id"`
+	Label       string       `json:"label"`
+	Description string       `json:"description"`
+	Source      MetricSource `json:"source"`
+	Expr        string       `json:"-"` // SQL expression, written against the source's alias
+	Unit        MetricUnit   `json:"unit"`
+	Currency    bool         `json:"currency"` // Whether the value is converted to the base currency when filtering and sorting
+	Balance     bool         `json:"balance"`  // Whether the value is a balance-sheet item, which may be converted at a different rate
+	Column      bool         `json:"column"`
+	Filterable  bool         `json:"filterable"`
+	Sortable    bool         `json:"sortable"`
+	Min         float64      `json:"min"` // Suggested slider range
+	Max         float64      `json:"max"`
+
+	// Trend kinds that can be computed for the metric, e.g. `revenue_cagr_5y`
+	Trends []MetricTrendKind `json:"trends,omitempty"`
+
+	// Sector-relative kinds that can be computed for the metric, e.g. `evebit_sector_pct`
+	Relatives []MetricRelativeKind `json:"relatives,omitempty"`
+
+	// Set if the metric is a trend of another metric
+	Trend *MetricTrend `json:"trend,omitempty"`
+
+	// Set if the metric is relative to the sector of another metric
+	Relative *MetricRelative `json:"relative,omitempty"`
+
+	// Set if the metric is the rank of a composite ranking, e.g. `ranking_cv1b3kuc2sg2ldhq1n10`
+	Ranking *xid.ID `json:"ranking,omitempty"`
+}
+
+// Pointer to the field in s that holds the metric's value, or nil if there is none.
+func (m Metric) Target(s *Screener) any {
+	if i, ok := screenerFields[m.ID]; ok {
+		return reflect.ValueOf(s).Elem().Field(i).Addr().Interface()
+	}
+
+	if v, ok := s.Metrics[m.ID]; ok {
+		return v
+	}
+
+	if s.Metrics == nil {
+		s.Metrics = make(MetricValues)
+	}
+
+	v := new(Nullable[float64])
+	s.Metrics[m.ID] = v
+
+	return v
+}
+
+// The range that the metric is filtered by, if any.
+func (m Metric) Range(f *ScreenerFilter) (r MinMax[float64]) {
+	if i, ok := screenerFilterFields[m.ID]; ok {
+		switch v := reflect.ValueOf(f).Elem().Field(i).Interface().(type) {
+		case MinMax[int]:
+			r = MinMax[float64]{Min: nullableFloat(v.Min), Max: nullableFloat(v.Max)}
+		case MinMax[float32]:
+			r = MinMax[float64]{Min: nullableFloat(v.Min), Max: nullableFloat(v.Max)}
+		}
+	}
+
+	for _, mr := range f.Ranges {
+		if mr.ID != m.ID {
+			continue
+		}
+
+		if mr.Min.Valid {
+			r.Min = mr.Min
+		}
+
+		if mr.Max.Valid {
+			r.Max = mr.Max
+		}
+	}
+
+	return
+}
+
+// Metrics that are filtered by a range, and their ranges.
+func (f *ScreenerFilter) RangedMetrics() iter.Seq2[Metric, MinMax[float64]] {
+	return func(yield func(Metric, MinMax[float64]) bool) {
+		for _, m := range Metrics {
+			if r := m.Range(f); m.Filterable && !r.IsZero() {
+				if !yield(m, r) {
+					return
+				}
+			}
+		}
+
+		for i, mr := range f.Ranges {
+			if _, ok := metricIndex[mr.ID]; ok || slices.ContainsFunc(f.Ranges[:i], func(r MetricRange) bool { return r.ID == mr.ID }) {
+				continue
+			}
+
+			if m, ok := LookupMetric(mr.ID); ok && m.Filterable {
+				if !yield(m, m.Range(f)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func nullableFloat[T Number](v Nullable[T]) Nullable[float64] {
+	return Nullable[float64]{Content: float64(v.Content), Valid: v.Valid}
+}
+
+func LookupMetric(id MetricID) (m Metric, ok bool) {
+	if i, ok := metricIndex[id]; ok {
+		return Metrics[i], true
+	}
+
+	if m, ok = lookupTrendMetric(id); ok {
+		return
+	}
+
+	if m, ok = lookupRelativeMetric(id); ok {
+		return
+	}
+
+	return lookupRankingMetric(id)
+}
+
+var (
+	metricIndex          = indexMetrics()
+	screenerFields       = indexFields(reflect.TypeFor[Screener](), "json")
+	screenerFilterFields = indexFields(reflect.TypeFor[ScreenerFilter](), "query")
+)
+
+func indexMetrics() map[MetricID]int {
+	idx := make(map[MetricID]int, len(Metrics))
+
+	for i := range Metrics {
+		idx[Metrics[i].ID] = i
+		Metrics[i].Trends = trendKinds(Metrics[i])
+		Metrics[i].Relatives = relativeKinds(Metrics[i])
+	}
+
+	return idx
+}
+
+func indexFields(typ reflect.Type, tag string) map[MetricID]int {
+	idx := make(map[MetricID]int, typ.NumField())
+
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get(tag), ",")
+
+		if _, ok := metricIndex[MetricID(name)]; ok {
+			idx[MetricID(name)] = i
+		}
+	}
+
+	return idx
+}
+
+// All metrics that the screener can select, filter or sort by.
+var Metrics = []Metric{
+	{ID: "name", Label: "Name", Description: "Company name.", Source: MetricSourceCompanies, Expr: "c.name", Unit: MetricUnitText, Sortable: true},
+	{ID: "currency", Label: "Currency", Description: "Reporting currency.", Source: MetricSourceCurrencies, Expr: "curr.name", Unit: MetricUnitText, Sortable: true},
+	{ID: ScreenerColumnSector, Label: "Sector", Description: "Company sector.", Source: MetricSourceSectors, Expr: "sec.name", Unit: MetricUnitText, Column: true, Sortable: true},
+	{ID: ScreenerColumnMagicRank, Label: "Magic Formula", Description: "Rank by return on capital plus rank by earnings yield, lower is better.", Source: MetricSourceMagicFormulaRankings, Expr: "m.rank", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Min: 1, Max: 1000},
+
+	// Static financials
+	{ID: "capital_expenditures", Label: "Capital Expenditures", Description: "Cash spent on fixed assets.", Source: MetricSourceFinancials, Expr: "f.capital_expenditures", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "cash_and_equivalents", Label: "Cash and Equivalents", Description: "Cash and cash equivalents.", Source: MetricSourceFinancials, Expr: "f.cash_and_equivalents", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "cost_of_revenue", Label: "Cost of Revenue", Description: "Direct costs of goods and services sold.", Source: MetricSourceFinancials, Expr: "f.cost_of_revenue", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "current_debt", Label: "Current Debt", Description: "Debt due within a year.", Source: MetricSourceFinancials, Expr: "f.current_debt", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "ebit", Label: "EBIT", Description: "Earnings before interest and taxes.", Source: MetricSourceFinancials, Expr: "f.ebit", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "equity", Label: "Equity", Description: "Total stockholders' equity.", Source: MetricSourceFinancials, Expr: "f.equity", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "free_cash_flow", Label: "Free Cash Flow", Description: "Operating cash flow less capital expenditures.", Source: MetricSourceFinancials, Expr: "f.free_cash_flow", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "gross_operating_profit", Label: "Gross Operating Profit", Description: "Revenue less cost of revenue.", Source: MetricSourceFinancials, Expr: "f.gross_operating_profit", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "long_term_debt", Label: "Long Term Debt", Description: "Debt due after more than a year.", Source: MetricSourceFinancials, Expr: "f.long_term_debt", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "net_income", Label: "Net Income", Description: "Profit after all expenses and taxes.", Source: MetricSourceFinancials, Expr: "f.net_income", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "number_of_shares", Label: "Number of Shares", Description: "Shares outstanding.", Source: MetricSourceFinancials, Expr: "f.number_of_shares", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Max: 10000000000},
+	{ID: "operating_cash_flow", Label: "Operating Cash Flow", Description: "Cash generated by operations.", Source: MetricSourceFinancials, Expr: "f.operating_cash_flow", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "ppe", Label: "PPE", Description: "Net property, plant and equipment.", Source: MetricSourceFinancials, Expr: "f.ppe", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: ScreenerColumnRevenue, Label: "Revenue", Description: "Total revenue.", Source: MetricSourceFinancials, Expr: "f.revenue", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "short_term_investments", Label: "Short Term Investments", Description: "Investments that mature within a year.", Source: MetricSourceFinancials, Expr: "f.short_term_investments", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "total_assets", Label: "Total Assets", Description: "Total assets.", Source: MetricSourceFinancials, Expr: "f.total_assets", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "total_liabilities", Label: "Total Liabilities", Description: "Total liabilities.", Source: MetricSourceFinancials, Expr: "f.total_liabilities", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+
+	// Derived financials
+	{ID: "eps", Label: "EPS", Description: "Net income per share.", Source: MetricSourceDerivedFinancials, Expr: "df.eps", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 1000},
+	{ID: "evebit", Label: "EV/EBIT", Description: "Enterprise value divided by EBIT.", Source: MetricSourceDerivedFinancials, Expr: "df.evebit", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 12},
+	{ID: "pb", Label: "P/B", Description: "Market cap divided by book value.", Source: MetricSourceDerivedFinancials, Expr: "df.pb", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 3},
+	{ID: "pe", Label: "P/E", Description: "Market cap divided by net income.", Source: MetricSourceDerivedFinancials, Expr: "df.pe", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Min: 5, Max: 25},
+	{ID: "ps", Label: "P/S", Description: "Market cap divided by revenue.", Source: MetricSourceDerivedFinancials, Expr: "df.ps", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 5},
+	{ID: "operating_margin", Label: "Operating Margin", Description: "EBIT divided by revenue.", Source: MetricSourceDerivedFinancials, Expr: "df.operating_margin", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "net_margin", Label: "Net Margin", Description: "Net income divided by revenue.", Source: MetricSourceDerivedFinancials, Expr: "df.net_margin", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "roe", Label: "ROE", Description: "Return on equity.", Source: MetricSourceDerivedFinancials, Expr: "df.roe", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "roc", Label: "ROC", Description: "Return on capital, EBIT divided by tangible capital employed.", Source: MetricSourceDerivedFinancials, Expr: "df.roc", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "liabilities_to_equity", Label: "Liabilities to Equity", Description: "Total liabilities divided by equity.", Source: MetricSourceDerivedFinancials, Expr: "df.liabilities_to_equity", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 2},
+	{ID: "debt_to_ebit", Label: "Debt to Ebit", Description: "Net debt divided by EBIT.", Source: MetricSourceDerivedFinancials, Expr: "df.debt_to_ebit", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 3},
+	{ID: "debt_to_assets", Label: "Debt to Assets", Description: "Total debt divided by total assets.", Source: MetricSourceDerivedFinancials, Expr: "df.debt_to_assets", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "cash_conversion", Label: "Cash Conversion Rate", Description: "Operating cash flow divided by net income.", Source: MetricSourceDerivedFinancials, Expr: "df.cash_conversion", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 2},
+	{ID: "market_cap", Label: "Market Cap", Description: "Share price times shares outstanding.", Source: MetricSourceDerivedFinancials, Expr: "df.market_cap", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+	{ID: "enterprise_value", Label: "Enterprise Value", Description: "Market cap plus net debt.", Source: MetricSourceDerivedFinancials, Expr: "df.enterprise_value", Unit: MetricUnitMoney, Currency: true, Balance: true, Column: true, Filterable: true, Sortable: true, Max: 1000000},
+
+	// Dividends
+	{ID: "dividends_per_share", Label: "Dividends per Share", Description: "Dividends per share with an ex-date in the fiscal year, in the currency that the shares trade in.", Source: MetricSourceDividendMetrics, Expr: "dm.dividends_per_share", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 100},
+	{ID: "dividend_yield", Label: "Dividend Yield", Description: "Dividends per share divided by the share price.", Source: MetricSourceDividendMetrics, Expr: "dm.dividend_yield", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 0.1},
+	{ID: "payout_ratio", Label: "Payout Ratio", Description: "Dividends paid divided by net income.", Source: MetricSourceDividendMetrics, Expr: "dm.payout_ratio", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "dividend_growth_years", Label: "Dividend Growth Years", Description: "Consecutive fiscal years of increased dividends per share.", Source: MetricSourceDividendMetrics, Expr: "dm.growth_years::float8", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Max: 25},
+
+	// Fundamental scores, null when an input is missing, along with whether every input was there
+	{ID: "f_score", Label: "Piotroski F-Score", Description: "Number of passed signals of profitability, leverage and efficiency, out of 9. Incomplete until there are share counts per fiscal year to tell dilution by.", Source: MetricSourceFinancialScores, Expr: "fs.f_score::float8", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Max: 9},
+	{ID: "z_score", Label: "Altman Z-Score", Description: "Bankruptcy risk, where below 1.8 signals distress.", Source: MetricSourceFinancialScores, Expr: "fs.z_score", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 10},
+	{ID: "m_score", Label: "Beneish M-Score", Description: "Earnings manipulation risk, where above -1.78 signals manipulation.", Source: MetricSourceFinancialScores, Expr: "fs.m_score", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Min: -5, Max: 0},
+	{ID: "f_score_complete", Label: "F-Score Complete", Description: "1 if every Piotroski signal could be computed, 0 if an input is missing.", Source: MetricSourceFinancialScores, Expr: "case when fs.company_id is not null then (fs.f_score is not null)::int::float8 end", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "z_score_complete", Label: "Z-Score Complete", Description: "1 if every Altman ratio could be computed, 0 if an input is missing.", Source: MetricSourceFinancialScores, Expr: "case when fs.company_id is not null then (fs.z_score is not null)::int::float8 end", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "m_score_complete", Label: "M-Score Complete", Description: "1 if every Beneish index could be computed, 0 if an input is missing.", Source: MetricSourceFinancialScores, Expr: "case when fs.company_id is not null then (fs.m_score is not null)::int::float8 end", Unit: MetricUnitCount, Column: true, Filterable: true, Sortable: true, Max: 1},
+
+	// Share price metrics, as of the valuation date of the fiscal year or the latest price
+	{ID: "return_1m", Label: "1M Return", Description: "Share price change over one month.", Source: MetricSourcePriceMetrics, Expr: "pm.return_1m", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.3, Max: 0.3},
+	{ID: "return_3m", Label: "3M Return", Description: "Share price change over three months.", Source: MetricSourcePriceMetrics, Expr: "pm.return_3m", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.5, Max: 0.5},
+	{ID: "return_6m", Label: "6M Return", Description: "Share price change over six months.", Source: MetricSourcePriceMetrics, Expr: "pm.return_6m", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.5, Max: 1},
+	{ID: "return_12m", Label: "12M Return", Description: "Share price change over twelve months.", Source: MetricSourcePriceMetrics, Expr: "pm.return_12m", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.5, Max: 1},
+	{ID: "high_52w_distance", Label: "Distance from 52W High", Description: "Share price relative to the highest price over a year.", Source: MetricSourcePriceMetrics, Expr: "pm.high_52w_distance", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.5, Max: 0},
+	{ID: "low_52w_distance", Label: "Distance from 52W Low", Description: "Share price relative to the lowest price over a year.", Source: MetricSourcePriceMetrics, Expr: "pm.low_52w_distance", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "ma50_position", Label: "Price to 50D MA", Description: "Share price relative to its 50-day moving average.", Source: MetricSourcePriceMetrics, Expr: "pm.ma50_position", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.3, Max: 0.3},
+	{ID: "ma200_position", Label: "Price to 200D MA", Description: "Share price relative to its 200-day moving average.", Source: MetricSourcePriceMetrics, Expr: "pm.ma200_position", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Min: -0.3, Max: 0.3},
+	{ID: "rsi", Label: "RSI", Description: "14-day relative strength index, from 0 to 100.", Source: MetricSourcePriceMetrics, Expr: "pm.rsi", Unit: MetricUnitRatio, Column: true, Filterable: true, Sortable: true, Max: 100},
+	{ID: "volatility", Label: "Volatility", Description: "Annualized standard deviation of the daily returns over a year.", Source: MetricSourcePriceMetrics, Expr: "pm.volatility", Unit: MetricUnitPercent, Column: true, Filterable: true, Sortable: true, Max: 1},
+	{ID: "traded_value", Label: "Traded Value", Description: "Average daily traded value over three months, converted from the currency that the shares trade in.", Source: MetricSourcePriceMetrics, Expr: "pm.traded_value", Unit: MetricUnitMoney, Currency: true, Column: true, Filterable: true, Sortable: true, Max: 100},
+}
+
+// Values of metrics that have no dedicated field in Screener, keyed by metric ID.
+type MetricValues map[MetricID]*Nullable[float64]
+
+// TypeDescription implements registry.TypeDescriber.
+func (MetricValues) TypeDescription(reg *registry.Registry) registry.TypeDescription {
+	return registry.TypeDescription{
+		Schema: func(tags reflect.StructTag) (schema openapi.Schema, err error) {
+			return &openapi.Object{
+				Description: "Values of selected metrics without a dedicated field, keyed by metric ID.",
+			}, nil
+		},
+	}
+}
+
+type MetricRange struct {
+	ID MetricID
+	MinMax[float64]
+}
+
+// Ranges for any filterable metric, including trends, e.g. `pe::15,revenue_cagr_5y:0.08:`. Takes
+// precedence over the dedicated query parameters.
+type MetricRanges []MetricRange
+
+// TypeDescription implements registry.TypeDescriber.
+func (MetricRanges) TypeDescription(reg *registry.Registry) registry.TypeDescription {
+	return registry.TypeDescription{
+		Schema: func(tags reflect.StructTag) (schema openapi.Schema, err error) {
+			return &openapi.String{
+				Description: "Comma-separated list of `metric:min:max`, where either bound may be empty.",
+			}, nil
+		},
+		Parser: func(tags reflect.StructTag) (registry.Parser, error) {
+			return func(p unsafe.Pointer, s string) (err error) {
+				return (*MetricRanges)(p).parse(s)
+			}, nil
+		},
+	}
+}
+
+func (r *MetricRanges) parse(s string) (err error) {
+	for _, raw := range strings.Split(s, ",") {
+		parts := strings.Split(raw, ":")
+
+		if len(parts) != 3 {
+			return ErrUnknownMetric.Detailed("expected `metric:min:max`, got \""+raw+"\"", "ranges")
+		}
+
+		id := MetricID(parts[0])
+
+		if m, ok := LookupMetric(id); !ok || !m.Filterable {
+			return ErrUnknownMetric.Detailed("unknown metric \""+parts[0]+"\"", "ranges")
+		}
+
+		mr := MetricRange{ID: id}
+
+		if mr.Min, err = valueFrom[float64](parts[1]); err != nil {
+			return
+		}
+
+		if mr.Max, err = valueFrom[float64](parts[2]); err != nil {
+			return
+		}
+
+		*r = append(*r, mr)
+	}
+
+	return
+}
+
+func (r MetricRanges) String() string {
+	var b strings.Builder
+
+	for i, mr := range r {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(string(mr.ID) + ":" + formatNullable(mr.Min) + ":" + formatNullable(mr.Max))
+	}
+
+	return b.String()
+}
+
+// MetricRanges is encoded as JSON in the same format as in a query.
+func (r MetricRanges) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(r.String())
+}
+
+func (r *MetricRanges) UnmarshalJSON(b []byte) (err error) {
+	var s *string
+
+	*r = nil
+
+	if err = json.Unmarshal(b, &s); err != nil || s == nil || *s == "" {
+		return
+	}
+
+	return r.parse(*s)
+}
@@ -0,0 +1,84 @@
+package domain
+
+import "strings"
+
+// How a metric is compared with the other companies in the same sector and fiscal year.
+type MetricRelativeKind string
+
+const (
+	MetricRelativePercentile MetricRelativeKind = "pct" // Percentile rank from 0 (lowest) to 1 (highest)
+	MetricRelativeZScore     MetricRelativeKind = "z"   // Standard deviations from the sector mean
+)
+
+// Which companies a company is compared with in sector-relative metrics.
+type SectorScope string
+
+const (
+	SectorScopeAll         SectorScope = "all"
+	SectorScopeCountry     SectorScope = "country"
+	SectorScopeMarketPlace SectorScope = "marketplace"
+)
+
+// A metric relative to the company's sector, e.g. `evebit_sector_pct`.
+type MetricRelative struct {
+	Metric MetricID           `json:"metric"`
+	Kind   MetricRelativeKind `json:"kind"`
+}
+
+func (r MetricRelative) ID() MetricID {
+	return MetricID(string(r.Metric) + "_sector_" + string(r.Kind))
+}
+
+// Relative kinds that are supported for a metric.
+func relativeKinds(m Metric) []MetricRelativeKind {
+	if m.Source != MetricSourceDerivedFinancials {
+		return nil
+	}
+
+	return []MetricRelativeKind{MetricRelativePercentile, MetricRelativeZScore}
+}
+
+// Resolves sector-relative metrics, e.g. `evebit_sector_pct` or `roc_sector_z`.
+func lookupRelativeMetric(id MetricID) (m Metric, ok bool) {
+	var rel MetricRelative
+
+	for _, kind := range []MetricRelativeKind{MetricRelativePercentile, MetricRelativeZScore} {
+		if base, found := strings.CutSuffix(string(id), "_sector_"+string(kind)); found {
+			rel = MetricRelative{Metric: MetricID(base), Kind: kind}
+		}
+	}
+
+	i, ok := metricIndex[rel.Metric]
+
+	if !ok || len(relativeKinds(Metrics[i])) == 0 {
+		return m, false
+	}
+
+	base := Metrics[i]
+
+	m = Metric{
+		ID:         rel.ID(),
+		Source:     base.Source,
+		Expr:       `"` + string(base.ID) + `_sector".` + string(rel.Kind),
+		Column:     true,
+		Filterable: true,
+		Sortable:   true,
+		Relative:   &rel,
+	}
+
+	switch rel.Kind {
+	case MetricRelativePercentile:
+		m.Label = base.Label + " Sector Percentile"
+		m.Description = "Percentile rank of " + base.Label + " within the sector, from 0 (lowest) to 1 (highest)."
+		m.Unit = MetricUnitPercent
+		m.Min, m.Max = 0, 1
+
+	case MetricRelativeZScore:
+		m.Label = base.Label + " Sector Z-Score"
+		m.Description = "Standard deviations of " + base.Label + " from the sector mean."
+		m.Unit = MetricUnitRatio
+		m.Min, m.Max = -3, 3
+	}
+
+	return
+}
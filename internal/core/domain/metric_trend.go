@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// How a metric is aggregated over a window of past fiscal years.
+type MetricTrendKind string
+
+const (
+	MetricTrendCAGR    MetricTrendKind = "cagr"   // Compound annual growth rate
+	MetricTrendAverage MetricTrendKind = "avg"    // Average, requires a value for every year
+	MetricTrendStdDev  MetricTrendKind = "stddev" // Sample standard deviation, requires a value for every year
+	MetricTrendStreak  MetricTrendKind = "streak" // Consecutive years with a positive value, ending at the fiscal year
+)
+
+const MaxTrendYears = 10
+
+// A metric aggregated over the window of fiscal years ending at the filtered fiscal year. For
+// CAGR, Years is the number of growth periods, i.e. it compares the fiscal year with the one Years
+// before it.
+type MetricTrend struct {
+	Metric MetricID        `json:"metric"`
+	Kind   MetricTrendKind `json:"kind"`
+	Years  int             `json:"years"`
+}
+
+func (t MetricTrend) ID() MetricID {
+	return MetricID(string(t.Metric) + "_" + string(t.Kind) + "_" + strconv.Itoa(t.Years) + "y")
+}
+
+// Trend kinds that are supported for a metric.
+func trendKinds(m Metric) []MetricTrendKind {
+	if m.Source != MetricSourceFinancials && m.Source != MetricSourceDerivedFinancials {
+		return nil
+	}
+
+	switch m.Unit {
+	case MetricUnitMoney, MetricUnitCount:
+		return []MetricTrendKind{MetricTrendCAGR, MetricTrendAverage, MetricTrendStdDev, MetricTrendStreak}
+	case MetricUnitRatio, MetricUnitPercent:
+		return []MetricTrendKind{MetricTrendAverage, MetricTrendStdDev, MetricTrendStreak}
+	}
+
+	return nil
+}
+
+var trendPattern = regexp.MustCompile(`^(.+)_(cagr|avg|stddev|streak)_(\d+)y$`)
+
+// Resolves trend metrics, e.g. `revenue_cagr_5y` or `roc_avg_3y`.
+func lookupTrendMetric(id MetricID) (m Metric, ok bool) {
+	match := trendPattern.FindStringSubmatch(string(id))
+
+	if match == nil {
+		return
+	}
+
+	base, ok := metricIndex[MetricID(match[1])]
+
+	if !ok {
+		return
+	}
+
+	years, err := strconv.Atoi(match[3])
+
+	if err != nil || years < 1 || years > MaxTrendYears {
+		return m, false
+	}
+
+	trend := MetricTrend{
+		Metric: MetricID(match[1]),
+		Kind:   MetricTrendKind(match[2]),
+		Years:  years,
+	}
+
+	if trend.Kind != MetricTrendCAGR && years < 2 {
+		return m, false
+	}
+
+	return newTrendMetric(Metrics[base], trend)
+}
+
+func newTrendMetric(base Metric, trend MetricTrend) (m Metric, ok bool) {
+	for _, kind := range trendKinds(base) {
+		if kind == trend.Kind {
+			ok = true
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	years := strconv.Itoa(trend.Years)
+
+	m = Metric{
+		ID:         trend.ID(),
+		Source:     base.Source,
+		Expr:       `"` + string(trend.ID()) + `".value`,
+		Unit:       base.Unit,
+		Currency:   base.Currency,
+		Balance:    base.Balance,
+		Column:     true,
+		Filterable: true,
+		Sortable:   true,
+		Min:        base.Min,
+		Max:        base.Max,
+		Trend:      &trend,
+	}
+
+	switch trend.Kind {
+	case MetricTrendCAGR:
+		m.Label = base.Label + " CAGR (" + years + "y)"
+		m.Description = "Compound annual growth rate of " + base.Label + " over " + years + " years."
+		m.Unit = MetricUnitPercent
+		m.Currency = false
+		m.Min, m.Max = -0.5, 0.5
+
+	case MetricTrendAverage:
+		m.Label = base.Label + " Average (" + years + "y)"
+		m.Description = "Average " + base.Label + " over the last " + years + " years."
+
+	case MetricTrendStdDev:
+		m.Label = base.Label + " Std. Dev. (" + years + "y)"
+		m.Description = "Standard deviation of " + base.Label + " over the last " + years + " years."
+		m.Min = 0
+
+	case MetricTrendStreak:
+		m.Label = base.Label + " Positive Streak (" + years + "y)"
+		m.Description = "Consecutive years with positive " + base.Label + ", out of the last " + years + "."
+		m.Unit = MetricUnitCount
+		m.Currency = false
+		m.Min, m.Max = 0, float64(trend.Years)
+	}
+
+	return
+}
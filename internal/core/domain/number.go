@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"errors"
 	"reflect"
 	"strconv"
@@ -12,7 +13,7 @@ import (
 )
 
 type Number interface {
-	int | float32
+	int | float32 | float64
 }
 
 func valueFrom[T Number](s string) (t Nullable[T], err error) {
@@ -36,6 +37,13 @@ func valueFrom[T Number](s string) (t Nullable[T], err error) {
 			return
 		}
 		t = Optional(T(f), true)
+	case float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+		t = Optional(T(f), true)
 	default:
 		return t, errors.New("invalid type")
 	}
@@ -56,34 +64,83 @@ func (m MinMax[T]) IsZero() bool {
 func (MinMax[T]) TypeDescription(reg *registry.Registry) registry.TypeDescription {
 	return registry.TypeDescription{
 		Schema: func(tags reflect.StructTag) (schema openapi.Schema, err error) {
-			return reg.Schema(reflect.TypeFor[T](), tags)
+			return &openapi.String{
+				Description: "Comma-separated min and max, where either may be empty, e.g. `5,25`.",
+			}, nil
 		},
 		Parser: func(tags reflect.StructTag) (registry.Parser, error) {
 			return func(p unsafe.Pointer, s string) error {
-				ptr := (*MinMax[T])(p)
-
-				raw := strings.Split(s, ",")
-				min, err := valueFrom[T](raw[0])
-				if err != nil {
-					return err
-				}
-				max, err := valueFrom[T](raw[1])
-				if err != nil {
-					return err
-				}
-				if min.Valid && max.Valid && min.Content > max.Content {
-					min.Valid = false
-				}
-				if min.Valid && max.Valid && max.Content < min.Content {
-					max.Valid = false
-				}
-				*ptr = MinMax[T]{
-					Min: min,
-					Max: max,
-				}
-
-				return err
+				return (*MinMax[T])(p).parse(s)
 			}, nil
 		},
 	}
 }
+
+func (m *MinMax[T]) parse(s string) (err error) {
+	raw := strings.Split(s, ",")
+
+	if len(raw) != 2 {
+		return errors.New("expected `min,max`")
+	}
+
+	min, err := valueFrom[T](raw[0])
+	if err != nil {
+		return
+	}
+	max, err := valueFrom[T](raw[1])
+	if err != nil {
+		return
+	}
+	if min.Valid && max.Valid && min.Content > max.Content {
+		min.Valid = false
+	}
+	if min.Valid && max.Valid && max.Content < min.Content {
+		max.Valid = false
+	}
+	*m = MinMax[T]{
+		Min: min,
+		Max: max,
+	}
+
+	return
+}
+
+func (m MinMax[T]) String() string {
+	return formatNullable(m.Min) + "," + formatNullable(m.Max)
+}
+
+func formatNullable[T Number](v Nullable[T]) string {
+	if !v.Valid {
+		return ""
+	}
+
+	switch c := any(v.Content).(type) {
+	case int:
+		return strconv.Itoa(c)
+	case float32:
+		return strconv.FormatFloat(float64(c), 'f', -1, 32)
+	}
+
+	return strconv.FormatFloat(float64(v.Content), 'f', -1, 64)
+}
+
+// MinMax is encoded as JSON in the same format as in a query, so that it can be stored and
+// sent back as-is.
+func (m MinMax[T]) MarshalJSON() ([]byte, error) {
+	if m.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(m.String())
+}
+
+func (m *MinMax[T]) UnmarshalJSON(b []byte) (err error) {
+	var s *string
+
+	if err = json.Unmarshal(b, &s); err != nil || s == nil {
+		*m = MinMax[T]{}
+		return
+	}
+
+	return m.parse(*s)
+}
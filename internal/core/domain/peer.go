@@ -0,0 +1,40 @@
+package domain
+
+import "github.com/rs/xid"
+
+// A derived metric of a company compared with the companies in its sector, for a fiscal year.
+// Monetary values are in millions of the base currency, so that they are comparable.
+type PeerMetric struct {
+	Metric     MetricID          `json:"metric"`
+	Label      string            `json:"label"`
+	Unit       MetricUnit        `json:"unit"`
+	Value      Nullable[float64] `json:"value"`
+	Count      int               `json:"count"` // Companies in the sector with a value, including the company itself
+	P25        Nullable[float64] `json:"p25"`
+	Median     Nullable[float64] `json:"median"`
+	P75        Nullable[float64] `json:"p75"`
+	Percentile Nullable[float64] `json:"percentile"` // Percentile rank of the value, from 0 (lowest) to 1 (highest)
+}
+
+// A company in the same sector, with its size relative to the compared company.
+type Peer struct {
+	CompanyID   xid.ID      `json:"companyId"`
+	Name        string      `json:"name"`
+	CountryCode CountryCode `json:"countryCode"`
+	MarketCap   float64     `json:"marketCap"` // In millions of the base currency
+	SizeRatio   float64     `json:"sizeRatio"` // Market cap relative to the company's, e.g. 2 for twice as large
+}
+
+type PeerComparison struct {
+	CompanyID  xid.ID       `json:"companyId"`
+	SectorID   xid.ID       `json:"sectorId"`
+	FiscalYear int          `json:"fiscalYear"`
+	Metrics    []PeerMetric `json:"metrics"`
+	Peers      []Peer       `json:"peers"` // Closest in market cap first
+}
+
+type PeerFilter struct {
+	FiscalYear  int         `query:"fiscalYear"`
+	SectorScope SectorScope `query:"sectorScope" enum:"all,country,marketplace" default:"all"`
+	Peers       int         `query:"peers" min:"0" max:"50"` // How many of the closest companies in size to list, none by default
+}
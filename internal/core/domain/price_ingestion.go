@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// Days of prices that are fetched for companies without prices, and that are searched for gaps.
+const PriceBackfillDays = 365
+
+// Days between two prices of a company that are considered a gap, which is more than weekends and
+// holidays.
+const PriceGapDays = 7
+
+// The state of the daily price ingestion of a company.
+type PriceIngestion struct {
+	CompanyID   xid.ID              `json:"companyId"`
+	OrderbookID string              `json:"orderbookId"`
+	Attempted   Nullable[time.Time] `json:"attempted"`
+	Succeeded   Nullable[time.Time] `json:"succeeded"` // The latest successful ingestion
+	Error       string              `json:"error"`     // Of the latest attempt, empty if it succeeded
+	LastDate    Nullable[time.Time] `json:"lastDate"`  // The latest stored price
+	GapDate     Nullable[time.Time] `json:"gapDate"`   // The price before the earliest gap within the backfill period
+}
+
+// The date that prices are fetched from: the latest stored price, so that it's updated if it was
+// fetched during the day, or the earliest gap to backfill.
+func (p PriceIngestion) From(now time.Time) time.Time {
+	from := now.AddDate(0, 0, -PriceBackfillDays)
+
+	if p.LastDate.Valid {
+		from = p.LastDate.Content
+	}
+
+	if p.GapDate.Valid && p.GapDate.Content.Before(from) {
+		from = p.GapDate.Content
+	}
+
+	return from
+}
+
+type PriceIngestionFilter struct {
+	Failed            bool      `query:"failed"`            // Only those whose latest attempt failed
+	NotSucceededSince time.Time `query:"notSucceededSince"` // Only those that haven't succeeded since
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/webmafia/papi/errors"
+)
+
+var ErrInvalidRanking = errors.NewFrozenError("INVALID_RANKING", "Invalid ranking")
+
+type RankingDirection string
+
+const (
+	RankingDirectionAsc  RankingDirection = "asc"  // Lower values are better, e.g. EV/EBIT
+	RankingDirectionDesc RankingDirection = "desc" // Higher values are better, e.g. ROE
+)
+
+// One metric of a composite ranking. Companies are ranked by each factor, and the percentiles of
+// the factor ranks are weighted together into a score.
+type RankingFactor struct {
+	Metric        MetricID         `json:"metric"`
+	Weight        float64          `json:"weight"`
+	Direction     RankingDirection `json:"direction" enum:"asc,desc" default:"desc"`
+	SectorNeutral bool             `json:"sectorNeutral"` // Rank the factor within each sector instead of the whole market
+}
+
+// A user-defined composite ranking, like the magic formula but with any metrics and weights.
+type Ranking struct {
+	ID          xid.ID          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Factors     []RankingFactor `json:"factors"`
+	Created     time.Time       `json:"created"`
+	Updated     time.Time       `json:"updated"`
+}
+
+// The metric that holds the ranking's rank in the screener.
+func (r Ranking) MetricID() MetricID {
+	return MetricID("ranking_" + r.ID.String())
+}
+
+type RankingFilter struct {
+	Order   string `query:"order" enum:"asc,desc" default:"asc"`
+	OrderBy string `query:"orderBy" enum:"name,created,updated" default:"name"`
+	Limit   int    `query:"limit" min:"1" max:"500" default:"50"`
+	Offset  int    `query:"offset" min:"0"`
+	Search  string `query:"search"`
+}
+
+// A company's place in a composite ranking, shaped like MagicRank.
+type CompositeRank struct {
+	Company    IDAndName    `json:"company"`
+	FiscalYear int          `json:"fiscalYear"`
+	Factors    []FactorRank `json:"factors"`
+	Score      float64      `json:"score"` // Weighted percentile of the factor ranks, from 0 (worst) to 1 (best)
+	Rank       int          `json:"rank"`
+}
+
+type FactorRank struct {
+	Metric MetricID `json:"metric"`
+	Value  float64  `json:"value"`
+	Rank   int      `json:"rank"` // Within the sector if the factor is sector neutral
+}
+
+type CompositeRankFilter struct {
+	Order      string   `query:"order" enum:"asc,desc" default:"asc"`
+	OrderBy    string   `query:"orderBy" enum:"rank" default:"rank"`
+	Limit      int      `query:"limit" min:"1" max:"500" default:"50"`
+	Offset     int      `query:"offset" min:"0"`
+	Include    []xid.ID `query:"include"`
+	Search     string   `query:"search"`
+	FiscalYear int      `query:"fiscalYear"`
+}
+
+// Ensures that a ranking has at least one factor, and that all factors are numeric metrics with a
+// positive weight.
+func (r Ranking) Validate() error {
+	if len(r.Factors) == 0 {
+		return ErrInvalidRanking.Detailed("a ranking needs at least one factor", "factors")
+	}
+
+	for _, f := range r.Factors {
+		m, ok := LookupMetric(f.Metric)
+
+		if !ok || !m.Filterable || m.Unit == MetricUnitText || m.Ranking != nil {
+			return ErrUnknownMetric.Detailed("cannot rank by \""+string(f.Metric)+"\"", "factors")
+		}
+
+		if f.Weight <= 0 {
+			return ErrInvalidRanking.Detailed("weights must be positive", "factors")
+		}
+
+		if f.Direction != RankingDirectionAsc && f.Direction != RankingDirectionDesc {
+			return ErrInvalidRanking.Detailed("direction must be asc or desc", "factors")
+		}
+	}
+
+	return nil
+}
+
+// Resolves the rank of a composite ranking, e.g. `ranking_cv1b3kuc2sg2ldhq1n10`. The ranking itself
+// isn't looked up, so it might not exist.
+func lookupRankingMetric(id MetricID) (m Metric, ok bool) {
+	s, found := strings.CutPrefix(string(id), "ranking_")
+
+	if !found {
+		return
+	}
+
+	rankingId, err := xid.FromString(s)
+
+	if err != nil {
+		return m, false
+	}
+
+	return Metric{
+		ID:          id,
+		Label:       "Composite Rank",
+		Description: "Rank in a user-defined composite ranking, where 1 is the best.",
+		Source:      MetricSource(id),
+		Expr:        `"` + string(id) + `".rank::float8`,
+		Unit:        MetricUnitCount,
+		Column:      true,
+		Filterable:  true,
+		Sortable:    true,
+		Min:         1,
+		Max:         1000,
+		Ranking:     &rankingId,
+	}, true
+}
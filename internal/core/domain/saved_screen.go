@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/webmafia/papi/errors"
+)
+
+var (
+	ErrTooFewSnapshots = errors.NewFrozenError("TOO_FEW_SNAPSHOTS", "The screen has less than two snapshots", 404)
+	ErrUnknownSnapshot = errors.NewFrozenError("UNKNOWN_SNAPSHOT", "The snapshot doesn't exist for the screen", 404)
+)
+
+type ScreenVisibility string
+
+const (
+	ScreenVisibilityPrivate ScreenVisibility = "private"
+	ScreenVisibilityPublic  ScreenVisibility = "public"
+)
+
+// A named ScreenerFilter that can be run again later. The filter is stored in the same format as
+// it's sent, so stored screens keep working when metrics are added.
+type SavedScreen struct {
+	ID          xid.ID           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Owner       string           `json:"owner"`
+	Visibility  ScreenVisibility `json:"visibility" enum:"private,public" default:"private"`
+	Filter      ScreenerFilter   `json:"filter"`
+	Tracked     bool             `json:"tracked"`                                     // Whether the screen's members are snapshotted daily
+	TrackLimit  int              `json:"trackLimit" min:"1" max:"1000" default:"100"` // How many of the top results are snapshotted
+	Created     time.Time        `json:"created"`
+	Updated     time.Time        `json:"updated"`
+}
+
+type SavedScreenFilter struct {
+	Order   string `query:"order" enum:"asc,desc" default:"asc"`
+	OrderBy string `query:"orderBy" enum:"name,created,updated" default:"name"`
+	Limit   int    `query:"limit" min:"1" max:"500" default:"50"`
+	Offset  int    `query:"offset" min:"0"`
+	Search  string `query:"search"`
+	Owner   string `query:"owner"` // If set, the owner's private screens are included
+	Tracked bool   // Only tracked screens, regardless of visibility
+}
+
+// Overrides the paging and fiscal year of a saved screen's filter when it's run.
+type SavedScreenRun struct {
+	Limit      int      `query:"limit" min:"1" max:"1000" default:"50"`
+	Offset     int      `query:"offset" min:"0"`
+	FiscalYear int      `query:"fiscalYear"`
+	Cursor     string   `query:"cursor"`
+	SkipTotal  bool     `query:"skipTotal"`
+	Currency   string   `query:"currency" enum:"SEK,EUR,DKK,ISK,USD"` // Overrides the display currency of the screen
+	FXPolicy   FXPolicy `query:"fxPolicy" enum:"standard,average,closing"`
+}
+
+// The members of a tracked screen at one point in time, e.g. the magic formula top 30 for a screen
+// ordered by magicRank with a TrackLimit of 30.
+type ScreenSnapshot struct {
+	ID         xid.ID                 `json:"id"`
+	ScreenID   xid.ID                 `json:"screenId"`
+	FiscalYear int                    `json:"fiscalYear"`
+	Count      int                    `json:"count"`
+	Created    time.Time              `json:"created"`
+	Members    []ScreenSnapshotMember `json:"-"`
+}
+
+type ScreenSnapshotMember struct {
+	CompanyID xid.ID
+	Rank      int
+}
+
+type ScreenSnapshotFilter struct {
+	Limit    int `query:"limit" min:"1" max:"500" default:"50"`
+	Offset   int `query:"offset" min:"0"`
+	ScreenID xid.ID
+}
+
+type ScreenChange string
+
+const (
+	ScreenChangeAdded   ScreenChange = "added"
+	ScreenChangeRemoved ScreenChange = "removed"
+	ScreenChangeMoved   ScreenChange = "moved"
+)
+
+// A company that entered, left or changed rank in a screen between two snapshots.
+type ScreenMemberChange struct {
+	Company  IDAndName       `json:"company"`
+	Change   ScreenChange    `json:"change"`
+	FromRank Nullable[int64] `json:"fromRank"`
+	ToRank   Nullable[int64] `json:"toRank"`
+}
+
+// Snapshots to compare. Defaults to the two latest.
+type ScreenDiffFilter struct {
+	From xid.ID `query:"from"`
+	To   xid.ID `query:"to"`
+}
+
+// Whether a company was a member of a screen in a snapshot.
+type ScreenMembership struct {
+	SnapshotID xid.ID          `json:"snapshotId"`
+	FiscalYear int             `json:"fiscalYear"`
+	Created    time.Time       `json:"created"`
+	Member     bool            `json:"member"`
+	Rank       Nullable[int64] `json:"rank"`
+}
@@ -0,0 +1,70 @@
+package domain
+
+import "github.com/rs/xid"
+
+// A component of a score: a Piotroski signal that is 1 if passed, or an Altman ratio or Beneish
+// index. The ID is the column it's stored as.
+type ScoreComponent struct {
+	ID    string            `json:"id"`
+	Label string            `json:"label"`
+	Value Nullable[float64] `json:"value"`
+}
+
+type Score struct {
+	Value      Nullable[float64] `json:"value"`
+	Complete   bool              `json:"complete"` // Whether every component could be computed, otherwise there's no value
+	Components []ScoreComponent  `json:"components"`
+}
+
+// A new score with the components of a model, without values.
+func NewScore(components []ScoreComponent) Score {
+	return Score{Components: append([]ScoreComponent(nil), components...)}
+}
+
+// The fundamental scores of a company for a fiscal year.
+type FinancialScores struct {
+	CompanyID  xid.ID `json:"companyId"`
+	FiscalYear int    `json:"fiscalYear"`
+	Piotroski  Score  `json:"piotroski"` // F-score, 0-9 where higher is better
+	Altman     Score  `json:"altman"`    // Z-score, where below 1.8 signals distress
+	Beneish    Score  `json:"beneish"`   // M-score, where above -1.78 signals earnings manipulation
+}
+
+type FinancialScoresFilter struct {
+	Limit   int      `query:"limit" min:"1" max:"500" default:"50"`
+	Offset  int      `query:"offset" min:"0"`
+	Include []xid.ID `query:"include"`
+}
+
+var (
+	PiotroskiComponents = []ScoreComponent{
+		{ID: "f_roa", Label: "Positive net income"},
+		{ID: "f_cfo", Label: "Positive operating cash flow"},
+		{ID: "f_delta_roa", Label: "Higher return on assets"},
+		{ID: "f_accruals", Label: "Operating cash flow above net income"},
+		{ID: "f_delta_leverage", Label: "Lower long term debt to assets"},
+		{ID: "f_delta_liquidity", Label: "Higher current ratio"},
+		{ID: "f_no_dilution", Label: "No new shares issued"},
+		{ID: "f_delta_margin", Label: "Higher gross margin"},
+		{ID: "f_delta_turnover", Label: "Higher asset turnover"},
+	}
+
+	AltmanComponents = []ScoreComponent{
+		{ID: "z_working_capital", Label: "Working capital to total assets"},
+		{ID: "z_retained_earnings", Label: "Retained earnings to total assets"},
+		{ID: "z_ebit", Label: "EBIT to total assets"},
+		{ID: "z_market_value", Label: "Market cap to total liabilities"},
+		{ID: "z_sales", Label: "Revenue to total assets"},
+	}
+
+	BeneishComponents = []ScoreComponent{
+		{ID: "m_dsri", Label: "Days sales in receivables index"},
+		{ID: "m_gmi", Label: "Gross margin index"},
+		{ID: "m_aqi", Label: "Asset quality index"},
+		{ID: "m_sgi", Label: "Sales growth index"},
+		{ID: "m_depi", Label: "Depreciation index"},
+		{ID: "m_sgai", Label: "SG&A expenses index"},
+		{ID: "m_tata", Label: "Total accruals to total assets"},
+		{ID: "m_lvgi", Label: "Leverage index"},
+	}
+)
@@ -1,9 +1,17 @@
 package domain
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
 	"github.com/rs/xid"
+	"github.com/webmafia/papi/errors"
 )
 
+var ErrInvalidCursor = errors.NewFrozenError("INVALID_CURSOR", "Invalid cursor")
+
 type Screener struct {
 	CompanyId   xid.ID           `json:"companyId"`
 	Name        string           `json:"name"`
@@ -11,6 +19,20 @@ type Screener struct {
 	CountryCode CountryCode      `json:"countryCode"`
 	MagicRank   Nullable[int64]  `json:"magicRank"`
 	Sector      Nullable[string] `json:"sector"`
+	Cursor      string           `json:"cursor,omitempty"` // Pass as `cursor` to get the rows after this one
+
+	// Display currency, when monetary values are converted from the reporting currency
+	DisplayCurrency     string            `json:"displayCurrency,omitempty"`
+	ExchangeRate        Nullable[float64] `json:"exchangeRate,omitzero"`        // Units of the display currency per unit of the reporting currency, for income and cash-flow items
+	BalanceExchangeRate Nullable[float64] `json:"balanceExchangeRate,omitzero"` // Same as ExchangeRate, but for balance-sheet items
+
+	// Current valuation, when derived financials are computed with the latest price, i.e. in current
+	// valuation and TTM mode
+	PriceDate  Nullable[time.Time] `json:"priceDate,omitzero"`
+	StalePrice bool                `json:"stalePrice,omitempty"` // The price is older than the filter's StaleDays
+
+	// Latest period of the trailing twelve months in TTM mode, e.g. "2025-q3"
+	TTMThrough Nullable[string] `json:"ttmThrough,omitzero"`
 
 	// Static financials
 	CapitalExpenditures  Nullable[int64] `json:"capital_expenditures"`
@@ -45,42 +67,73 @@ type Screener struct {
 	DebtToEbit          Nullable[float64] `json:"debt_to_ebit"`
 	DebtToAssets        Nullable[float64] `json:"debt_to_assets"`
 	CashConversion      Nullable[float64] `json:"cash_conversion"`
+	MarketCap           Nullable[int64]   `json:"market_cap"`
+	EnterpriseValue     Nullable[int64]   `json:"enterprise_value"`
+
+	// Trends etc.
+	Metrics MetricValues `json:"metrics,omitempty"`
 }
 
 type ScreenerFilter struct {
-	Order      string   `query:"order" enum:"asc,desc" default:"asc"`
-	OrderBy    string   `query:"orderby" enum:"name,magicRank,sector,revenue,cost_of_revenue,gross_operating_profit,ebit,net_income,total_assets,total_liabilities,cash_and_equivalents,short_term_investments,long_term_debt,current_debt,equity,operating_cash_flow,capital_expenditures,free_cash_flow,number_of_shares,ppe,eps,pe,evebit,ps,pb" default:"name"`
-	Limit      int      `query:"limit" min:"1" max:"500" default:"50"`
-	Offset     int      `query:"offset" min:"0"`
-	Include    []xid.ID `query:"include"`
-	Search     string   `query:"search"`
-	FiscalYear int      `query:"fiscalYear"`
-	Columns    []string `query:"columns"`
+	Order       string       `query:"order" json:"order,omitempty" enum:"asc,desc" default:"asc"`
+	OrderBy     MetricID     `query:"orderby" json:"orderby,omitempty" default:"name"`
+	Limit       int          `query:"limit" json:"limit,omitempty" min:"1" max:"500" default:"50"`
+	Offset      int          `query:"offset" json:"offset,omitempty" min:"0"`
+	Include     []xid.ID     `query:"include" json:"include,omitempty"`
+	Search      string       `query:"search" json:"search,omitempty"`
+	FiscalYear  int          `query:"fiscalYear" json:"fiscalYear,omitempty"`
+	Columns     []MetricID   `query:"columns" json:"columns,omitempty"`
+	Ranges      MetricRanges `query:"ranges" json:"ranges,omitempty"`
+	SectorScope SectorScope  `query:"sectorScope" json:"sectorScope,omitempty" enum:"all,country,marketplace" default:"all"`
+	Currency    string       `query:"currency" json:"currency,omitempty" enum:"SEK,EUR,DKK,ISK,USD"` // Converts monetary columns to this currency
+	FXPolicy    FXPolicy     `query:"fxPolicy" json:"fxPolicy,omitempty" enum:"standard,average,closing" default:"standard"`
+	Period      PeriodKind   `query:"period" json:"period,omitempty" enum:"annual,ttm" default:"annual"` // Financials of the fiscal year, or of the trailing twelve months with derived metrics at the latest price
+	Valuation   Valuation    `query:"valuation" json:"valuation,omitempty" enum:"historical,current" default:"historical"`
+	StaleDays   int          `query:"staleDays" json:"staleDays,omitempty" min:"1" max:"365" default:"5"` // Days before the latest price is stale in current valuation and TTM mode
+
+	// Universe
+	Countries           []CountryCode     `query:"countries" json:"countries,omitempty" enum:"se,dk,fi,is"`
+	ExcludeCountries    []CountryCode     `query:"excludeCountries" json:"excludeCountries,omitempty" enum:"se,dk,fi,is"`
+	MarketPlaces        []MarketPlaceCode `query:"marketPlaces" json:"marketPlaces,omitempty" enum:"xsto,xcse,xhel,xice"`
+	ExcludeMarketPlaces []MarketPlaceCode `query:"excludeMarketPlaces" json:"excludeMarketPlaces,omitempty" enum:"xsto,xcse,xhel,xice"`
+	Sectors             []xid.ID          `query:"sectors" json:"sectors,omitempty"`
+	ExcludeSectors      []xid.ID          `query:"excludeSectors" json:"excludeSectors,omitempty"`
+	Currencies          []xid.ID          `query:"currencies" json:"currencies,omitempty"`
+	ExcludeCurrencies   []xid.ID          `query:"excludeCurrencies" json:"excludeCurrencies,omitempty"`
+	Expression          string            `json:"expression,omitempty"`
+
+	// Keyset pagination, which replaces the offset when set
+	Cursor    string `query:"cursor" json:"-"`
+	SkipTotal bool   `query:"skipTotal" json:"-"` // Don't count the total number of rows, which is then -1
 
 	// Static financials
-	CapitalExpenditures  MinMax[int] `query:"capital_expenditures"`   // min="0" max="1000000"
-	EBIT                 MinMax[int] `query:"ebit"`                   // min="0" max="1000000"
-	Equity               MinMax[int] `query:"equity"`                 // min="0" max="1000000"
-	GrossOperatingProfit MinMax[int] `query:"gross_operating_profit"` // min="0" max="1000000"
-	NetIncome            MinMax[int] `query:"net_income"`             // min="0" max="1000000"
-	OperatingCashFlow    MinMax[int] `query:"operating_cash_flow"`    // min="0" max="1000000"
-	Revenue              MinMax[int] `query:"revenue"`                // min="0" max="1000000"
+	CapitalExpenditures  MinMax[int] `query:"capital_expenditures" json:"capital_expenditures,omitzero"`     // min="0" max="1000000"
+	EBIT                 MinMax[int] `query:"ebit" json:"ebit,omitzero"`                                     // min="0" max="1000000"
+	Equity               MinMax[int] `query:"equity" json:"equity,omitzero"`                                 // min="0" max="1000000"
+	GrossOperatingProfit MinMax[int] `query:"gross_operating_profit" json:"gross_operating_profit,omitzero"` // min="0" max="1000000"
+	NetIncome            MinMax[int] `query:"net_income" json:"net_income,omitzero"`                         // min="0" max="1000000"
+	OperatingCashFlow    MinMax[int] `query:"operating_cash_flow" json:"operating_cash_flow,omitzero"`       // min="0" max="1000000"
+	Revenue              MinMax[int] `query:"revenue" json:"revenue,omitzero"`                               // min="0" max="1000000"
 
 	// Derived financials
-	EPS                 MinMax[float32] `query:"eps"`                   // min="0" max="1000"
-	EVEBIT              MinMax[float32] `query:"evebit"`                // min="0" max="12"
-	PB                  MinMax[float32] `query:"pb"`                    // min="0" max="3"
-	PE                  MinMax[float32] `query:"pe"`                    // min="5" max="25"
-	PS                  MinMax[float32] `query:"ps"`                    // min="0" max="5"
-	OperatingMargin     MinMax[float32] `query:"operating_margin"`      // min="0" max="1"
-	NetMargin           MinMax[float32] `query:"net_margin"`            // min="0" max="1"
-	ROE                 MinMax[float32] `query:"roe"`                   // min="0" max="1"
-	ROC                 MinMax[float32] `query:"roc"`                   // min="0" max="1"
-	LiabilitiesToEquity MinMax[float32] `query:"liabilities_to_equity"` // min="0" max="2"
-	DebtToEBIT          MinMax[float32] `query:"debt_to_ebit"`          // min="0" max="3"
-	DebtToAssets        MinMax[float32] `query:"debt_to_assets"`        // min="0" max="1"
-	CashConversion      MinMax[float32] `query:"cash_conversion"`       // min="0" max="2"
-	MagicRank           MinMax[int]     `query:"magicRank"`             // min="1" max="1000"
+	EPS                 MinMax[float32] `query:"eps" json:"eps,omitzero"`                                     // min="0" max="1000"
+	EVEBIT              MinMax[float32] `query:"evebit" json:"evebit,omitzero"`                               // min="0" max="12"
+	PB                  MinMax[float32] `query:"pb" json:"pb,omitzero"`                                       // min="0" max="3"
+	PE                  MinMax[float32] `query:"pe" json:"pe,omitzero"`                                       // min="5" max="25"
+	PS                  MinMax[float32] `query:"ps" json:"ps,omitzero"`                                       // min="0" max="5"
+	OperatingMargin     MinMax[float32] `query:"operating_margin" json:"operating_margin,omitzero"`           // min="0" max="1"
+	NetMargin           MinMax[float32] `query:"net_margin" json:"net_margin,omitzero"`                       // min="0" max="1"
+	ROE                 MinMax[float32] `query:"roe" json:"roe,omitzero"`                                     // min="0" max="1"
+	ROC                 MinMax[float32] `query:"roc" json:"roc,omitzero"`                                     // min="0" max="1"
+	LiabilitiesToEquity MinMax[float32] `query:"liabilities_to_equity" json:"liabilities_to_equity,omitzero"` // min="0" max="2"
+	DebtToEBIT          MinMax[float32] `query:"debt_to_ebit" json:"debt_to_ebit,omitzero"`                   // min="0" max="3"
+	DebtToAssets        MinMax[float32] `query:"debt_to_assets" json:"debt_to_assets,omitzero"`               // min="0" max="1"
+	CashConversion      MinMax[float32] `query:"cash_conversion" json:"cash_conversion,omitzero"`             // min="0" max="2"
+	MagicRank           MinMax[int]     `query:"magicRank" json:"magicRank,omitzero"`                         // min="1" max="1000"
+}
+
+type ScreenerQuery struct {
+	Expression string `json:"expression"`
 }
 
 type ScreenerColumn struct {
@@ -88,8 +141,46 @@ type ScreenerColumn struct {
 }
 
 const (
-	ScreenerColumnMagicRank = "magicRank"
-	ScreenerColumnRevenue   = "revenue"
-	ScreenerColumnSector    = "sector"
-	ScreenerColumnName      = "name"
+	ScreenerColumnMagicRank MetricID = "magicRank"
+	ScreenerColumnRevenue   MetricID = "revenue"
+	ScreenerColumnSector    MetricID = "sector"
+	ScreenerColumnName      MetricID = "name"
 )
+
+// The position of a row in the screener: its value of the ordered metric, and its company as a tie
+// breaker. Encoded as an opaque string.
+type ScreenerCursor struct {
+	Value any    `json:"v"`
+	ID    xid.ID `json:"id"`
+}
+
+func (c ScreenerCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ParseScreenerCursor(s string) (c ScreenerCursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return c, ErrInvalidCursor.Detailed("malformed cursor", "cursor")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err = dec.Decode(&c); err != nil || c.ID.IsNil() {
+		return c, ErrInvalidCursor.Detailed("malformed cursor", "cursor")
+	}
+
+	// Keep integers as integers, so that they compare exactly with integer columns
+	if n, ok := c.Value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			c.Value = i
+		} else if c.Value, err = n.Float64(); err != nil {
+			return c, ErrInvalidCursor.Detailed("malformed cursor", "cursor")
+		}
+	}
+
+	return c, nil
+}
@@ -17,6 +17,23 @@ type Share struct {
 	Average   float64   `json:"average"`
 }
 
+// The period that each bar of a price series covers.
+type ShareInterval string
+
+const (
+	ShareIntervalDay   ShareInterval = "day"
+	ShareIntervalWeek  ShareInterval = "week"
+	ShareIntervalMonth ShareInterval = "month"
+)
+
+type ShareFilter struct {
+	Include  []xid.ID      `query:"include"`
+	From     time.Time     `query:"from"` // Optional, inclusive
+	To       time.Time     `query:"to"`   // Optional, inclusive
+	Interval ShareInterval `query:"interval" enum:"day,week,month" default:"day"`
+	Adjusted bool          `query:"adjusted"` // Whether prices and volumes are adjusted for corporate actions
+}
+
 type Row struct {
 	DateTime    string `json:"dateTime"`
 	Open        string `json:"open"`
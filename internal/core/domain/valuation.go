@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Which share price the valuation multiples of the derived financials are computed with.
+type Valuation string
+
+const (
+	ValuationHistorical Valuation = "historical" // The price after the end of the fiscal year
+	ValuationCurrent    Valuation = "current"    // The latest price, against the most recent reported fiscal year
+)
+
+// Number of days after which the latest price is considered stale, unless the filter says otherwise.
+const DefaultStaleDays = 5
+
+// Whether a price used for current valuations is older than the filter allows.
+func (f ScreenerFilter) IsStale(priceDate time.Time) bool {
+	days := f.StaleDays
+
+	if days <= 0 {
+		days = DefaultStaleDays
+	}
+
+	return time.Since(priceDate) > time.Duration(days)*24*time.Hour
+}
@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+// When a materialized view was last refreshed.
+type ViewRefresh struct {
+	Name      string    `json:"name"`
+	Refreshed time.Time `json:"refreshed"`
+}
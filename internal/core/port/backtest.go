@@ -0,0 +1,15 @@
+package port
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/rs/xid"
+)
+
+type Backtest interface {
+	IterateClosingPrices(ctx context.Context, companyIds []xid.ID, dates []time.Time) iter.Seq2[*domain.ClosingPrice, error]
+	LastShareDate(ctx context.Context) (time.Time, error)
+}
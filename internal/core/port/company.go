@@ -22,6 +22,25 @@ type Company interface {
 	IterateFinancials(ctx context.Context, filters domain.FinancialFilter) iter.Seq2[*domain.Financials, error]
 	CountFinancials(ctx context.Context, filters domain.FinancialFilter) (int, error)
 	IterateFinancialsByMissingShare(ctx context.Context) iter.Seq2[*domain.Financials, error]
+	IterateFinancialScores(ctx context.Context, filters domain.FinancialScoresFilter) iter.Seq2[*domain.FinancialScores, error]
+	CountFinancialScores(ctx context.Context, filters domain.FinancialScoresFilter) (int, error)
 	CreateShare(ctx context.Context, share *domain.Share) error
-	IterateShares(ctx context.Context, filters domain.CompanyFilter) iter.Seq2[*domain.Share, error]
+	IterateShares(ctx context.Context, filters domain.ShareFilter) iter.Seq2[*domain.Share, error]
+
+	CreateDividend(ctx context.Context, dividend *domain.Dividend) error
+	IterateDividends(ctx context.Context, filters domain.DividendFilter) iter.Seq2[*domain.Dividend, error]
+	CountDividends(ctx context.Context, filters domain.DividendFilter) (int, error)
+	IterateTotalReturns(ctx context.Context, filters domain.TotalReturnFilter) iter.Seq2[*domain.TotalReturn, error]
+	CountTotalReturns(ctx context.Context, filters domain.TotalReturnFilter) (int, error)
+
+	SetCorporateAction(ctx context.Context, action *domain.CorporateAction) error
+	DeleteCorporateAction(ctx context.Context, action *domain.CorporateAction) error
+	IterateCorporateActions(ctx context.Context, filters domain.CorporateActionFilter) iter.Seq2[*domain.CorporateAction, error]
+	CountCorporateActions(ctx context.Context, filters domain.CorporateActionFilter) (int, error)
+	ReadPeerComparison(ctx context.Context, comparison *domain.PeerComparison, filters domain.PeerFilter) error
+}
+
+// Dividends of a company from an external source, both past and announced.
+type DividendFeed interface {
+	GetDividends(ctx context.Context, companyId xid.ID, orderbookId string) iter.Seq2[*domain.Dividend, error]
 }
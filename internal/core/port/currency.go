@@ -18,4 +18,5 @@ type Currency interface {
 	IterateCurrencies(ctx context.Context, filters domain.IDAndNameFilter) iter.Seq2[*domain.IDAndName, error]
 	IterateCurrencyRates(ctx context.Context, filters domain.IDAndNameFilter) iter.Seq2[*domain.CurrencyRate, error]
 	SetCurrencyRates(ctx context.Context, currencyRates []domain.CurrencyRate) (err error)
+	SetAnnualCurrencyRates(ctx context.Context, currencyRates []domain.AnnualCurrencyRate) (err error)
 }
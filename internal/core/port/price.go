@@ -0,0 +1,23 @@
+package port
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/rs/xid"
+)
+
+type Price interface {
+	Context
+
+	CreateShares(ctx context.Context, shares []domain.Share) error
+	IteratePriceIngestions(ctx context.Context, filters domain.PriceIngestionFilter) iter.Seq2[*domain.PriceIngestion, error]
+	SetPriceIngestion(ctx context.Context, ingestion *domain.PriceIngestion) error
+}
+
+// Daily share prices from an external source.
+type PriceFeed interface {
+	GetShares(ctx context.Context, companyId xid.ID, orderbookId string, from time.Time, to time.Time) iter.Seq2[*domain.Share, error]
+}
@@ -0,0 +1,21 @@
+package port
+
+import (
+	"context"
+	"iter"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/rs/xid"
+)
+
+type Ranking interface {
+	CreateRanking(ctx context.Context, ranking *domain.Ranking) error
+	ReadRanking(ctx context.Context, ranking *domain.Ranking) error
+	UpdateRanking(ctx context.Context, ranking *domain.Ranking) error
+	DeleteRanking(ctx context.Context, rankingId xid.ID) error
+	IterateRankings(ctx context.Context, filters domain.RankingFilter) iter.Seq2[*domain.Ranking, error]
+	CountRankings(ctx context.Context, filters domain.RankingFilter) (int, error)
+
+	IterateCompositeRanks(ctx context.Context, ranking domain.Ranking, filters domain.CompositeRankFilter) iter.Seq2[*domain.CompositeRank, error]
+	CountCompositeRanks(ctx context.Context, ranking domain.Ranking, filters domain.CompositeRankFilter) (int, error)
+}
@@ -0,0 +1,26 @@
+package port
+
+import (
+	"context"
+	"iter"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/rs/xid"
+)
+
+type SavedScreen interface {
+	Context
+
+	CreateSavedScreen(ctx context.Context, screen *domain.SavedScreen) error
+	ReadSavedScreen(ctx context.Context, screen *domain.SavedScreen) error
+	UpdateSavedScreen(ctx context.Context, screen *domain.SavedScreen) error
+	DeleteSavedScreen(ctx context.Context, screenId xid.ID) error
+	IterateSavedScreens(ctx context.Context, filters domain.SavedScreenFilter) iter.Seq2[*domain.SavedScreen, error]
+	CountSavedScreens(ctx context.Context, filters domain.SavedScreenFilter) (int, error)
+
+	CreateScreenSnapshot(ctx context.Context, snapshot *domain.ScreenSnapshot) error
+	IterateScreenSnapshots(ctx context.Context, filters domain.ScreenSnapshotFilter) iter.Seq2[*domain.ScreenSnapshot, error]
+	CountScreenSnapshots(ctx context.Context, filters domain.ScreenSnapshotFilter) (int, error)
+	IterateScreenChanges(ctx context.Context, screenId xid.ID, from xid.ID, to xid.ID) iter.Seq2[*domain.ScreenMemberChange, error]
+	IterateScreenMembership(ctx context.Context, screenId xid.ID, companyId xid.ID) iter.Seq2[*domain.ScreenMembership, error]
+}
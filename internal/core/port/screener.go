@@ -8,8 +8,12 @@ import (
 )
 
 type Screener interface {
+	Context
+
 	IterateScreener(ctx context.Context, filter domain.ScreenerFilter) iter.Seq2[*domain.Screener, error]
 	CountScreener(ctx context.Context, filter domain.ScreenerFilter) (int, error)
 	IterateMagicRanks(ctx context.Context, filter domain.MagicRankFilter) iter.Seq2[*domain.MagicRank, error]
 	CountMagicRanks(ctx context.Context, filter domain.MagicRankFilter) (int, error)
+	IterateAggregates(ctx context.Context, filter domain.AggregateFilter) iter.Seq2[*domain.Aggregate, error]
+	CountAggregates(ctx context.Context, filter domain.AggregateFilter) (int, error)
 }
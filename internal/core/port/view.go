@@ -0,0 +1,13 @@
+package port
+
+import (
+	"context"
+	"iter"
+
+	"github.com/dagulv/screener/internal/core/domain"
+)
+
+type View interface {
+	RefreshViews(ctx context.Context) error
+	IterateViewRefreshes(ctx context.Context) iter.Seq2[*domain.ViewRefresh, error]
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"io"
+	"math"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+	"github.com/xuri/excelize/v2"
+)
+
+// Companies without a closing price this close to a rebalance can't be bought at it.
+const backtestMaxPriceAge = 10 * 24 * time.Hour
+
+type Backtest struct {
+	store         port.Backtest
+	screenerStore port.Screener
+}
+
+func NewBacktest(store port.Backtest, screenerStore port.Screener) Backtest {
+	return Backtest{
+		store:         store,
+		screenerStore: screenerStore,
+	}
+}
+
+// Simulates a strategy from its first rebalance until its end year, or the last closing price.
+// Holdings are bought at the latest close on or before each rebalance, and held until the next.
+func (s Backtest) Run(ctx context.Context, bt domain.Backtest) (result domain.BacktestResult, err error) {
+	if err = s.validate(&bt); err != nil {
+		return
+	}
+
+	end, err := s.store.LastShareDate(ctx)
+
+	if err != nil {
+		return
+	}
+
+	if bt.EndYear != 0 {
+		if e := time.Date(bt.EndYear+1, 1, 1, 0, 0, 0, 0, time.UTC); e.Before(end) {
+			end = e
+		}
+	}
+
+	start := time.Date(bt.StartYear, time.Month(1+domain.BacktestReportingLag), 1, 0, 0, 0, 0, time.UTC)
+
+	if !start.Before(end) {
+		return result, domain.ErrInvalidBacktest.Detailed("there are no prices after the first rebalance", "startYear")
+	}
+
+	value, benchmark := 1.0, 1.0
+	result.Curve = append(result.Curve, domain.BacktestPoint{Date: start, Value: value, Benchmark: benchmark})
+
+	for date := start; date.Before(end); date = date.AddDate(0, bt.Rebalance.Months(), 0) {
+		next := date.AddDate(0, bt.Rebalance.Months(), 0)
+
+		if next.After(end) {
+			next = end
+		}
+
+		// Month ends until the next rebalance, which is the last point
+		points := make([]time.Time, 0, bt.Rebalance.Months())
+
+		for p := date.AddDate(0, 1, 0); p.Before(next); p = p.AddDate(0, 1, 0) {
+			points = append(points, p)
+		}
+
+		points = append(points, next)
+
+		rebalancing, values, err := s.rebalance(ctx, bt, date, points)
+
+		if err != nil {
+			return result, err
+		}
+
+		benchmarks, err := s.benchmark(ctx, bt.Filter, date, points)
+
+		if err != nil {
+			return result, err
+		}
+
+		for i, p := range points {
+			result.Curve = append(result.Curve, domain.BacktestPoint{
+				Date:      p,
+				Value:     value * values[i],
+				Benchmark: benchmark * benchmarks[i],
+			})
+		}
+
+		rebalancing.Return = values[len(values)-1] - 1
+		rebalancing.Benchmark = benchmarks[len(benchmarks)-1] - 1
+		value *= values[len(values)-1]
+		benchmark *= benchmarks[len(benchmarks)-1]
+
+		result.Rebalances = append(result.Rebalances, rebalancing)
+	}
+
+	result.Portfolio = backtestStats(result.Curve, func(p domain.BacktestPoint) float64 { return p.Value })
+	result.Benchmark = backtestStats(result.Curve, func(p domain.BacktestPoint) float64 { return p.Benchmark })
+
+	return
+}
+
+func (s Backtest) validate(bt *domain.Backtest) (err error) {
+	if bt.StartYear < 1900 {
+		return domain.ErrInvalidBacktest.Detailed("a start year is required", "startYear")
+	}
+
+	if bt.EndYear != 0 && bt.EndYear < bt.StartYear {
+		return domain.ErrInvalidBacktest.Detailed("the end year is before the start year", "endYear")
+	}
+
+	if bt.Holdings == 0 {
+		bt.Holdings = 30
+	}
+
+	if bt.Holdings < 1 || bt.Holdings > 100 {
+		return domain.ErrInvalidBacktest.Detailed("holdings must be between 1 and 100", "holdings")
+	}
+
+	if bt.Rebalance == "" {
+		bt.Rebalance = domain.BacktestRebalanceYearly
+	}
+
+	if bt.Weighting == "" {
+		bt.Weighting = domain.BacktestWeightingEqual
+	}
+
+	if !bt.Ranking.IsNil() {
+		bt.Filter.OrderBy = domain.Ranking{ID: bt.Ranking}.MetricID()
+		bt.Filter.Order = "asc"
+	}
+
+	if bt.Filter.Order == "" {
+		bt.Filter.Order = "asc"
+	}
+
+	if bt.Filter.OrderBy == "" {
+		bt.Filter.OrderBy = domain.ScreenerColumnName
+	}
+
+	if bt.Filter.SectorScope == "" {
+		bt.Filter.SectorScope = domain.SectorScopeAll
+	}
+
+	// Current prices and the latest interim reports would leak into past rebalances
+	bt.Filter.Valuation = domain.ValuationHistorical
+	bt.Filter.Period = domain.PeriodKindAnnual
+
+	return validateScreenerFilter(bt.Filter)
+}
+
+// Selects the holdings at a rebalance, and returns their value at each point relative to the
+// rebalance.
+func (s Backtest) rebalance(ctx context.Context, bt domain.Backtest, date time.Time, points []time.Time) (rebalancing domain.BacktestRebalancing, values []float64, err error) {
+	filter := bt.Filter
+	filter.FiscalYear = domain.BacktestFiscalYear(date)
+	filter.Offset = 0
+	filter.Limit = 2 * bt.Holdings
+
+	rebalancing.Date = date
+	rebalancing.FiscalYear = filter.FiscalYear
+
+	candidates := make([]domain.IDAndName, 0, filter.Limit)
+	ids := make([]xid.ID, 0, filter.Limit)
+
+	for row, err := range s.screenerStore.IterateScreener(ctx, filter) {
+		if err != nil {
+			return rebalancing, nil, err
+		}
+
+		candidates = append(candidates, domain.IDAndName{ID: row.CompanyId, Name: row.Name})
+		ids = append(ids, row.CompanyId)
+	}
+
+	prices, err := s.prices(ctx, ids, date, points)
+
+	if err != nil {
+		return
+	}
+
+	for _, c := range candidates {
+		if len(rebalancing.Holdings) == bt.Holdings {
+			break
+		}
+
+		if _, ok := prices[c.ID]; ok {
+			rebalancing.Holdings = append(rebalancing.Holdings, domain.BacktestHolding{Company: c})
+		}
+	}
+
+	n := len(rebalancing.Holdings)
+	values = make([]float64, len(points))
+
+	// Without holdings, the portfolio is held in cash until the next rebalance
+	if n == 0 {
+		for i := range values {
+			values[i] = 1
+		}
+
+		return
+	}
+
+	for i := range rebalancing.Holdings {
+		h := &rebalancing.Holdings[i]
+
+		switch bt.Weighting {
+		case domain.BacktestWeightingRank:
+			h.Weight = float64(n-i) / float64(n*(n+1)/2)
+		default:
+			h.Weight = 1 / float64(n)
+		}
+
+		p := prices[h.Company.ID]
+
+		for j := range points {
+			values[j] += h.Weight * p[j+1] / p[0]
+		}
+
+		h.Return = p[len(p)-1]/p[0] - 1
+	}
+
+	return
+}
+
+// Returns the value of an equally weighted portfolio of all companies in the filter's universe, at
+// each point relative to the rebalance.
+func (s Backtest) benchmark(ctx context.Context, f domain.ScreenerFilter, date time.Time, points []time.Time) (values []float64, err error) {
+	filter := domain.ScreenerFilter{
+		Order:               "asc",
+		OrderBy:             domain.ScreenerColumnName,
+		Limit:               100000,
+		FiscalYear:          domain.BacktestFiscalYear(date),
+		SectorScope:         domain.SectorScopeAll,
+		Countries:           f.Countries,
+		ExcludeCountries:    f.ExcludeCountries,
+		MarketPlaces:        f.MarketPlaces,
+		ExcludeMarketPlaces: f.ExcludeMarketPlaces,
+		Sectors:             f.Sectors,
+		ExcludeSectors:      f.ExcludeSectors,
+		Currencies:          f.Currencies,
+		ExcludeCurrencies:   f.ExcludeCurrencies,
+	}
+
+	ids := make([]xid.ID, 0, 1024)
+
+	for row, err := range s.screenerStore.IterateScreener(ctx, filter) {
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, row.CompanyId)
+	}
+
+	prices, err := s.prices(ctx, ids, date, points)
+
+	if err != nil {
+		return
+	}
+
+	values = make([]float64, len(points))
+
+	for _, p := range prices {
+		for j := range points {
+			values[j] += p[j+1] / p[0] / float64(len(prices))
+		}
+	}
+
+	if len(prices) == 0 {
+		for i := range values {
+			values[i] = 1
+		}
+	}
+
+	return
+}
+
+// Reads the total-return prices of companies at a rebalance and each point after it, so that
+// returns include dividends. Companies that can't be bought at the rebalance are left out.
+func (s Backtest) prices(ctx context.Context, ids []xid.ID, date time.Time, points []time.Time) (prices map[xid.ID][]float64, err error) {
+	prices = make(map[xid.ID][]float64, len(ids))
+
+	if len(ids) == 0 {
+		return
+	}
+
+	dates := append([]time.Time{date}, points...)
+	index := make(map[int64]int, len(dates))
+
+	for i, d := range dates {
+		index[d.Unix()] = i
+	}
+
+	for price, err := range s.store.IterateClosingPrices(ctx, ids, dates) {
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := index[price.Date.Unix()]
+
+		if !ok {
+			continue
+		}
+
+		if i == 0 && (price.Close <= 0 || date.Sub(price.PriceDate) > backtestMaxPriceAge) {
+			continue
+		}
+
+		p, ok := prices[price.CompanyID]
+
+		if !ok {
+			p = make([]float64, len(dates))
+			prices[price.CompanyID] = p
+		}
+
+		p[i] = price.TotalReturn
+	}
+
+	for id, p := range prices {
+		if p[0] == 0 {
+			delete(prices, id)
+			continue
+		}
+
+		// Carry the last price forward, e.g. when a company is delisted
+		for i := 1; i < len(p); i++ {
+			if p[i] == 0 {
+				p[i] = p[i-1]
+			}
+		}
+	}
+
+	return
+}
+
+func backtestStats(curve []domain.BacktestPoint, value func(domain.BacktestPoint) float64) (stats domain.BacktestStats) {
+	if len(curve) < 2 {
+		return
+	}
+
+	first, last := curve[0], curve[len(curve)-1]
+	stats.Return = value(last)/value(first) - 1
+
+	if years := last.Date.Sub(first.Date).Hours() / 24 / 365.25; years > 0 {
+		stats.CAGR = math.Pow(value(last)/value(first), 1/years) - 1
+	}
+
+	var sum, sumSq, peak float64
+
+	for i, p := range curve {
+		v := value(p)
+		peak = max(peak, v)
+
+		if peak > 0 {
+			stats.MaxDrawdown = max(stats.MaxDrawdown, 1-v/peak)
+		}
+
+		if i > 0 {
+			r := v/value(curve[i-1]) - 1
+			sum += r
+			sumSq += r * r
+		}
+	}
+
+	if n := float64(len(curve) - 1); n > 1 {
+		variance := (sumSq - sum*sum/n) / (n - 1)
+		stats.Volatility = math.Sqrt(max(variance, 0)) * math.Sqrt(12)
+	}
+
+	return
+}
+
+// Writes a backtest as an XLSX file with the summary, value curve and holdings on separate sheets.
+func (s Backtest) Download(ctx context.Context, bt domain.Backtest, w io.Writer) (err error) {
+	result, err := s.Run(ctx, bt)
+
+	if err != nil {
+		return
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const summary, curve, holdings = "Summary", "Curve", "Holdings"
+
+	if err = f.SetSheetName("Sheet1", summary); err != nil {
+		return
+	}
+
+	for _, sheet := range []string{curve, holdings} {
+		if _, err = f.NewSheet(sheet); err != nil {
+			return
+		}
+	}
+
+	rows := [][]any{
+		{"", "Portfolio", "Benchmark"},
+		{"Return", result.Portfolio.Return, result.Benchmark.Return},
+		{"CAGR", result.Portfolio.CAGR, result.Benchmark.CAGR},
+		{"Volatility", result.Portfolio.Volatility, result.Benchmark.Volatility},
+		{"Max Drawdown", result.Portfolio.MaxDrawdown, result.Benchmark.MaxDrawdown},
+	}
+
+	if err = setSheetRows(f, summary, 1, rows); err != nil {
+		return
+	}
+
+	rows = [][]any{{"Date", "Value", "Benchmark"}}
+
+	for _, p := range result.Curve {
+		rows = append(rows, []any{p.Date, p.Value, p.Benchmark})
+	}
+
+	if err = setSheetRows(f, curve, 1, rows); err != nil {
+		return
+	}
+
+	rows = [][]any{{"Date", "Fiscal Year", "Name", "Weight", "Return"}}
+
+	for _, r := range result.Rebalances {
+		for _, h := range r.Holdings {
+			rows = append(rows, []any{r.Date, r.FiscalYear, h.Company.Name, h.Weight, h.Return})
+		}
+	}
+
+	if err = setSheetRows(f, holdings, 1, rows); err != nil {
+		return
+	}
+
+	return f.Write(w)
+}
@@ -2,9 +2,9 @@ package service
 
 import (
 	"context"
+	"database/sql/driver"
 	"io"
 	"iter"
-	"strconv"
 
 	"github.com/dagulv/screener/internal/core/domain"
 	"github.com/dagulv/screener/internal/core/port"
@@ -17,14 +17,16 @@ type Company struct {
 	currencyStore port.Currency
 	sectorStore   port.Sector
 	screenerStore port.Screener
+	viewStore     port.View
 }
 
-func NewCompany(store port.Company, currencyStore port.Currency, sectorStore port.Sector, screenerStore port.Screener) Company {
+func NewCompany(store port.Company, currencyStore port.Currency, sectorStore port.Sector, screenerStore port.Screener, viewStore port.View) Company {
 	return Company{
 		store:         store,
 		currencyStore: currencyStore,
 		sectorStore:   sectorStore,
 		screenerStore: screenerStore,
+		viewStore:     viewStore,
 	}
 }
 
@@ -257,6 +259,20 @@ func (s Company) Iterate(ctx context.Context, filters domain.CompanyFilter) iter
 // 	return
 // }
 
+// Creates or corrects the financials of a fiscal year, quarter or half-year, and refreshes the views
+// so that the derived metrics, scores and trailing twelve months include them.
+func (s Company) CreateFinancials(ctx context.Context, financials *domain.Financials) (err error) {
+	if err = financials.Validate(); err != nil {
+		return
+	}
+
+	if err = s.store.CreateFinancials(ctx, financials); err != nil {
+		return
+	}
+
+	return s.viewStore.RefreshViews(ctx)
+}
+
 func (s Company) CountFinancials(ctx context.Context, filters domain.FinancialFilter) (int, error) {
 	return s.store.CountFinancials(ctx, filters)
 }
@@ -265,225 +281,147 @@ func (s Company) IterateFinancials(ctx context.Context, filters domain.Financial
 	return s.store.IterateFinancials(ctx, filters)
 }
 
-const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+func (s Company) IterateShares(ctx context.Context, filters domain.ShareFilter) iter.Seq2[*domain.Share, error] {
+	return s.store.IterateShares(ctx, filters)
+}
 
-func (s Company) DownloadFinancials(ctx context.Context, filters domain.ScreenerFilter, w io.Writer) (err error) {
-	financials := s.screenerStore.IterateScreener(ctx, filters)
+func (s Company) CountFinancialScores(ctx context.Context, filters domain.FinancialScoresFilter) (int, error) {
+	return s.store.CountFinancialScores(ctx, filters)
+}
 
-	if err != nil {
+func (s Company) IterateFinancialScores(ctx context.Context, filters domain.FinancialScoresFilter) iter.Seq2[*domain.FinancialScores, error] {
+	return s.store.IterateFinancialScores(ctx, filters)
+}
+
+// Creates or corrects a dividend, and refreshes the views so that the dividend metrics and total
+// returns include it.
+func (s Company) CreateDividend(ctx context.Context, dividend *domain.Dividend) (err error) {
+	if err = s.store.CreateDividend(ctx, dividend); err != nil {
+		return
+	}
+
+	return s.viewStore.RefreshViews(ctx)
+}
+
+func (s Company) CountDividends(ctx context.Context, filters domain.DividendFilter) (int, error) {
+	return s.store.CountDividends(ctx, filters)
+}
+
+func (s Company) IterateDividends(ctx context.Context, filters domain.DividendFilter) iter.Seq2[*domain.Dividend, error] {
+	return s.store.IterateDividends(ctx, filters)
+}
+
+func (s Company) CountTotalReturns(ctx context.Context, filters domain.TotalReturnFilter) (int, error) {
+	return s.store.CountTotalReturns(ctx, filters)
+}
+
+func (s Company) IterateTotalReturns(ctx context.Context, filters domain.TotalReturnFilter) iter.Seq2[*domain.TotalReturn, error] {
+	return s.store.IterateTotalReturns(ctx, filters)
+}
+
+// Creates or corrects a corporate action, and refreshes the views so that every derived metric is
+// adjusted by it.
+func (s Company) SetCorporateAction(ctx context.Context, action *domain.CorporateAction) (err error) {
+	if err = action.Validate(); err != nil {
+		return
+	}
+
+	if err = s.store.SetCorporateAction(ctx, action); err != nil {
 		return
 	}
 
+	return s.viewStore.RefreshViews(ctx)
+}
+
+func (s Company) DeleteCorporateAction(ctx context.Context, action *domain.CorporateAction) (err error) {
+	if err = s.store.DeleteCorporateAction(ctx, action); err != nil {
+		return
+	}
+
+	return s.viewStore.RefreshViews(ctx)
+}
+
+func (s Company) CountCorporateActions(ctx context.Context, filters domain.CorporateActionFilter) (int, error) {
+	return s.store.CountCorporateActions(ctx, filters)
+}
+
+func (s Company) IterateCorporateActions(ctx context.Context, filters domain.CorporateActionFilter) iter.Seq2[*domain.CorporateAction, error] {
+	return s.store.IterateCorporateActions(ctx, filters)
+}
+
+func (s Company) ReadPeerComparison(ctx context.Context, comparison *domain.PeerComparison, filters domain.PeerFilter) error {
+	return s.store.ReadPeerComparison(ctx, comparison, filters)
+}
+
+func (s Company) DownloadFinancials(ctx context.Context, filters domain.ScreenerFilter, w io.Writer) (err error) {
+	financials := s.screenerStore.IterateScreener(ctx, filters)
+
 	f := excelize.NewFile()
 	defer f.Close()
 
-	i := 1
+	if err = setTitle(f, filters.Columns, 1); err != nil {
+		return
+	}
+
+	// The currency and exchange rates that monetary values are converted with
+	rateCol := len(filters.Columns) + 1
+
+	if filters.Currency != "" {
+		if err = setCell(f, rateCol, 1, "Currency"); err != nil {
+			return
+		}
+
+		if err = setCell(f, rateCol+1, 1, "Exchange Rate"); err != nil {
+			return
+		}
+
+		if err = setCell(f, rateCol+2, 1, "Balance Exchange Rate"); err != nil {
+			return
+		}
+	}
+
+	i := 2
 	for financial, err := range financials {
 		if err != nil {
 			return err
 		}
-		if i == 1 {
-			if err = setTitle(f, financial, i); err != nil {
-				return err
-			}
-			i++
-		}
-		j := 0
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), financial.Name); err != nil {
+
+		if err = setCell(f, 0, i, financial.Name); err != nil {
 			return err
 		}
-		j++
-		if value, ok := financialField(financial.MagicRank); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.Sector); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.CapitalExpenditures); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.CashAndEquivalents); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.CostOfRevenue); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.CurrentDebt); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.EBIT); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.Equity); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.FreeCashFlow); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.GrossOperatingProfit); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.LongTermDebt); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.NetIncome); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.NumberOfShares); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.OperatingCashFlow); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.PPE); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.Revenue); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.ShortTermInvestments); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.TotalAssets); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.TotalLiabilities); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.EPS); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.EVEBIT); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.PB); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.PE); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.PS); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.OperatingMargin); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.NetMargin); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.ROE); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.ROC); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
-			}
-			j++
-		}
-		if value, ok := financialField(financial.LiabilitiesToEquity); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
+
+		for j, id := range filters.Columns {
+			metric, _ := domain.LookupMetric(id)
+
+			if v, ok := metric.Target(financial).(driver.Valuer); ok {
+				value, err := v.Value()
+
+				if err != nil {
+					return err
+				}
+
+				if err = setCell(f, j+1, i, value); err != nil {
+					return err
+				}
 			}
-			j++
 		}
-		if value, ok := financialField(financial.DebtToEbit); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
+
+		if filters.Currency != "" {
+			if err = setCell(f, rateCol, i, financial.DisplayCurrency); err != nil {
 				return err
 			}
-			j++
-		}
-		if value, ok := financialField(financial.DebtToAssets); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
+
+			if financial.ExchangeRate.Valid {
+				if err = setCell(f, rateCol+1, i, financial.ExchangeRate.Content); err != nil {
+					return err
+				}
 			}
-			j++
-		}
-		if value, ok := financialField(financial.CashConversion); ok {
-			if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), value); err != nil {
-				return err
+
+			if financial.BalanceExchangeRate.Valid {
+				if err = setCell(f, rateCol+2, i, financial.BalanceExchangeRate.Content); err != nil {
+					return err
+				}
 			}
-			j++
 		}
 
 		i++
@@ -492,212 +430,45 @@ func (s Company) DownloadFinancials(ctx context.Context, filters domain.Screener
 	return f.Write(w)
 }
 
-func setTitle(f *excelize.File, financial *domain.Screener, i int) (err error) {
-	j := 0
-	if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Name"); err != nil {
-		return err
-	}
-	j++
-	if _, ok := financialField(financial.MagicRank); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Magic Formula"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.Sector); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Sector"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.CapitalExpenditures); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Capital Expenditures"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.CashAndEquivalents); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Cash and Equivalents"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.CostOfRevenue); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Cost of Revenue"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.CurrentDebt); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Current Debt"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.EBIT); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "EBIT"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.Equity); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Equity"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.FreeCashFlow); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Free Cash Flow"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.GrossOperatingProfit); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Gross Operating Profit"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.LongTermDebt); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Long Term Debt"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.NetIncome); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Net Income"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.NumberOfShares); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Number of Shares"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.OperatingCashFlow); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Operating Cash Flow"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.PPE); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "PPE"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.Revenue); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Revenue"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.ShortTermInvestments); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Short Term Investments"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.TotalAssets); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Total Assets"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.TotalLiabilities); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Total Liabilities"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.EPS); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "EPS"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.EVEBIT); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "EV/EBIT"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.PB); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "P/B"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.PE); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "P/E"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.PS); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "P/S"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.OperatingMargin); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Operating Margin"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.NetMargin); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Net Margin"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.ROE); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "ROE"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.ROC); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "ROC"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.LiabilitiesToEquity); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Liabilities to Equity"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.DebtToEbit); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Debt to Ebit"); err != nil {
-			return err
-		}
-		j++
-	}
-	if _, ok := financialField(financial.DebtToAssets); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Debt to Assets"); err != nil {
-			return err
-		}
-		j++
+func setTitle(f *excelize.File, cols []domain.MetricID, i int) (err error) {
+	if err = setCell(f, 0, i, "Name"); err != nil {
+		return
 	}
-	if _, ok := financialField(financial.CashConversion); ok {
-		if err = f.SetCellValue("Sheet1", string(alphabet[j])+strconv.Itoa(i), "Cash Conversion Rate"); err != nil {
-			return err
+
+	for j, id := range cols {
+		metric, _ := domain.LookupMetric(id)
+
+		if err = setCell(f, j+1, i, metric.Label); err != nil {
+			return
 		}
-		j++
 	}
+
 	return
 }
 
-func financialField[T any](field domain.Nullable[T]) (T, bool) {
-	if field.Valid {
-		return field.Content, true
+func setCell(f *excelize.File, col int, row int, value any) (err error) {
+	return setSheetCell(f, "Sheet1", col, row, value)
+}
+
+func setSheetCell(f *excelize.File, sheet string, col int, row int, value any) (err error) {
+	cell, err := excelize.CoordinatesToCellName(col+1, row)
+
+	if err != nil {
+		return
+	}
+
+	return f.SetCellValue(sheet, cell, value)
+}
+
+// Writes rows of values to a sheet, starting at the given row.
+func setSheetRows(f *excelize.File, sheet string, row int, rows [][]any) (err error) {
+	for i, values := range rows {
+		for j, value := range values {
+			if err = setSheetCell(f, sheet, j, row+i, value); err != nil {
+				return
+			}
+		}
 	}
 
-	var t T
-	return t, false
+	return
 }
@@ -19,13 +19,15 @@ import (
 
 type Currency struct {
 	store     port.Currency
+	viewStore port.View
 	env       *env.Environment
 	scheduler gocron.Scheduler
 }
 
-func NewCurrency(store port.Currency, env *env.Environment, scheduler gocron.Scheduler) Currency {
+func NewCurrency(store port.Currency, viewStore port.View, env *env.Environment, scheduler gocron.Scheduler) Currency {
 	return Currency{
 		store:     store,
+		viewStore: viewStore,
 		env:       env,
 		scheduler: scheduler,
 	}
@@ -65,17 +67,13 @@ func (s Currency) Iterate(ctx context.Context, filters domain.IDAndNameFilter) i
 	return s.store.IterateCurrencies(ctx, filters)
 }
 
-type sumCount struct {
-	sum   float32
-	count int
-}
-
 func (s Currency) FetchCurrencyRates(ctx context.Context) (err error) {
 	currencies := make([]*domain.IDAndName, 0)
 	currencySymbols := make([]string, 0)
 	oldRates := make([]*domain.CurrencyRate, 0)
 	const startYear = 2020
 	now := time.Now()
+	ingested := false
 
 	for c, err := range s.store.IterateCurrencies(ctx, domain.IDAndNameFilter{}) {
 		if err != nil {
@@ -107,6 +105,11 @@ func (s Currency) FetchCurrencyRates(ctx context.Context) (err error) {
 					break
 				}
 
+				// Rates stored before closing rates were added are fetched again
+				if r.Closing == 0 {
+					continue
+				}
+
 				quartersSum += r.Quarter
 			}
 
@@ -120,18 +123,11 @@ func (s Currency) FetchCurrencyRates(ctx context.Context) (err error) {
 			continue
 		}
 
-		endQuarters := [4]time.Time{
-			time.Date(year, time.March, 31, 23, 0, 0, 0, time.UTC),
-			time.Date(year, time.June, 30, 23, 0, 0, 0, time.UTC),
-			time.Date(year, time.September, 30, 23, 0, 0, 0, time.UTC),
-			time.Date(year, time.December, 31, 23, 0, 0, 0, time.UTC),
-		}
-
 		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
 		end := time.Date(year, time.December, 31, 23, 59, 0, 0, time.UTC)
 
 		if end.After(now) {
-			for _, q := range endQuarters {
+			for _, q := range domain.QuarterEnds(year) {
 				if now.After(q) {
 					end = q
 					break
@@ -162,59 +158,40 @@ func (s Currency) FetchCurrencyRates(ctx context.Context) (err error) {
 			return err
 		}
 
-		newRates := make([]domain.CurrencyRate, 0, len(currencies)*4)
-		sumNewRates := make(map[string]*[4]sumCount)
+		newRates, err := domain.QuarterlyCurrencyRates(year, currencies, result.Rates)
 
-		for _, c := range currencies {
-			for q := range 4 {
-				cr := domain.CurrencyRate{
-					FiscalYear: year,
-					Quarter:    q,
-					Currency:   *c,
-				}
-				newRates = append(newRates, cr)
-			}
-			sumNewRates[c.Name] = &[4]sumCount{}
+		if err != nil {
+			return err
 		}
 
-		for ts, r := range result.Rates {
-			t, err := time.Parse(time.DateOnly, ts)
-			if err != nil {
-				return err
-			}
-			for s, rate := range r {
-				_, ok := sumNewRates[s]
+		if err = s.store.SetCurrencyRates(ctx, newRates); err != nil {
+			return err
+		}
 
-				if !ok {
-					continue
-				}
+		ingested = true
+	}
 
-				for i, q := range endQuarters {
-					if t.After(q) {
-						continue
-					}
+	if !ingested {
+		return
+	}
 
-					sumNewRates[s][i].count++
-					sumNewRates[s][i].sum += rate
-					break
-				}
-			}
-		}
+	if err = s.setAnnualCurrencyRates(ctx); err != nil {
+		return
+	}
 
-		for symbol, sum := range sumNewRates {
-			for i := range newRates {
-				if newRates[i].Currency.Name != symbol {
-					continue
-				}
+	return s.viewStore.RefreshViews(ctx)
+}
 
-				newRates[i].Rate = sum[newRates[i].Quarter].sum / float32(sum[newRates[i].Quarter].count)
-			}
-		}
+// Aggregates all quarterly rates into annual rates, which the views convert financials with.
+func (s Currency) setAnnualCurrencyRates(ctx context.Context) (err error) {
+	rates := make([]domain.CurrencyRate, 0)
 
-		if err = s.store.SetCurrencyRates(ctx, newRates); err != nil {
+	for r, err := range s.store.IterateCurrencyRates(ctx, domain.IDAndNameFilter{}) {
+		if err != nil {
 			return err
 		}
+		rates = append(rates, *r)
 	}
 
-	return
+	return s.store.SetAnnualCurrencyRates(ctx, domain.AnnualCurrencyRates(rates))
 }
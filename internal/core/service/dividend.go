@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/go-co-op/gocron/v2"
+)
+
+// Companies that are read at once when ingesting dividends.
+const dividendPageSize = 500
+
+type Dividend struct {
+	store     port.Company
+	feed      port.DividendFeed
+	viewStore port.View
+	scheduler gocron.Scheduler
+}
+
+func NewDividend(store port.Company, feed port.DividendFeed, viewStore port.View, scheduler gocron.Scheduler) Dividend {
+	return Dividend{
+		store:     store,
+		feed:      feed,
+		viewStore: viewStore,
+		scheduler: scheduler,
+	}
+}
+
+// Ingests dividends every Saturday morning, as they're announced well ahead of their ex-dates.
+func (s Dividend) StartJobs(ctx context.Context) (err error) {
+	_, err = s.scheduler.NewJob(gocron.WeeklyJob(1, gocron.NewWeekdays(time.Saturday), gocron.NewAtTimes(gocron.NewAtTime(6, 0, 0))), gocron.NewTask(s.IngestDividends), gocron.WithContext(ctx))
+	return
+}
+
+// Fetches and upserts the dividends of every company, so that corrected amounts replace the stored
+// ones. A company that fails is logged and retried by the next run, without stopping the others.
+func (s Dividend) IngestDividends(ctx context.Context) (err error) {
+	companies := make([]domain.Company, 0)
+	filter := domain.CompanyFilter{Order: "asc", OrderBy: "name", Limit: dividendPageSize}
+
+	for {
+		n := 0
+
+		for c, err := range s.store.IterateCompanies(ctx, filter) {
+			if err != nil {
+				return err
+			}
+
+			companies = append(companies, *c)
+			n++
+		}
+
+		if n < filter.Limit {
+			break
+		}
+
+		filter.Offset += n
+	}
+
+	var ingested bool
+
+	for _, company := range companies {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		n, fetchErr := s.ingestCompany(ctx, company)
+
+		if fetchErr != nil {
+			log.Printf("ingesting dividends of %s: %v", company.ID, fetchErr)
+		}
+
+		ingested = ingested || n > 0
+
+		// Don't hammer the source
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	if ingested {
+		return s.viewStore.RefreshViews(ctx)
+	}
+
+	return
+}
+
+// Fetches and upserts the dividends of a company, and returns how many there were.
+func (s Dividend) ingestCompany(ctx context.Context, company domain.Company) (n int, err error) {
+	for dividend, err := range s.feed.GetDividends(ctx, company.ID, company.OrderbookID) {
+		if err != nil {
+			return n, err
+		}
+
+		if err = s.store.CreateDividend(ctx, dividend); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"iter"
+	"log"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/go-co-op/gocron/v2"
+)
+
+// Prices that are upserted at once.
+const priceBatchSize = 500
+
+type Price struct {
+	store     port.Price
+	feed      port.PriceFeed
+	viewStore port.View
+	scheduler gocron.Scheduler
+}
+
+func NewPrice(store port.Price, feed port.PriceFeed, viewStore port.View, scheduler gocron.Scheduler) Price {
+	return Price{
+		store:     store,
+		feed:      feed,
+		viewStore: viewStore,
+		scheduler: scheduler,
+	}
+}
+
+// Ingests prices every weekday evening, after the Nordic exchanges have closed.
+func (s Price) StartJobs(ctx context.Context) (err error) {
+	_, err = s.scheduler.NewJob(gocron.WeeklyJob(1, gocron.NewWeekdays(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday), gocron.NewAtTimes(gocron.NewAtTime(19, 0, 0))), gocron.NewTask(s.IngestPrices), gocron.WithContext(ctx))
+	return
+}
+
+func (s Price) IterateIngestions(ctx context.Context, filters domain.PriceIngestionFilter) iter.Seq2[*domain.PriceIngestion, error] {
+	return s.store.IteratePriceIngestions(ctx, filters)
+}
+
+// Fetches the prices of every company that hasn't been ingested successfully today, from its latest
+// stored price or earliest gap. A company that fails is recorded and retried by the next run, without
+// stopping the others.
+func (s Price) IngestPrices(ctx context.Context) (err error) {
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	ingestions := make([]domain.PriceIngestion, 0)
+
+	for ingestion, err := range s.store.IteratePriceIngestions(ctx, domain.PriceIngestionFilter{NotSucceededSince: today}) {
+		if err != nil {
+			return err
+		}
+
+		ingestions = append(ingestions, *ingestion)
+	}
+
+	var ingested bool
+
+	for _, ingestion := range ingestions {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		n, fetchErr := s.ingestCompany(ctx, ingestion, now)
+
+		ingestion.Attempted = domain.Nullable[time.Time]{Content: now, Valid: true}
+		ingestion.Error = ""
+
+		if fetchErr != nil {
+			log.Printf("ingesting prices of %s: %v", ingestion.CompanyID, fetchErr)
+			ingestion.Error = fetchErr.Error()
+		} else {
+			ingestion.Succeeded = ingestion.Attempted
+		}
+
+		if err = s.store.SetPriceIngestion(ctx, &ingestion); err != nil {
+			return
+		}
+
+		ingested = ingested || n > 0
+
+		// Don't hammer the source
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	if ingested {
+		return s.viewStore.RefreshViews(ctx)
+	}
+
+	return
+}
+
+// Fetches and upserts the prices of a company in batches, and returns how many there were.
+func (s Price) ingestCompany(ctx context.Context, ingestion domain.PriceIngestion, now time.Time) (n int, err error) {
+	batch := make([]domain.Share, 0, priceBatchSize)
+
+	for share, err := range s.feed.GetShares(ctx, ingestion.CompanyID, ingestion.OrderbookID, ingestion.From(now), now) {
+		if err != nil {
+			return n, err
+		}
+
+		batch = append(batch, *share)
+
+		if len(batch) == priceBatchSize {
+			if err = s.store.CreateShares(ctx, batch); err != nil {
+				return n, err
+			}
+
+			n += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err = s.store.CreateShares(ctx, batch); err != nil {
+			return
+		}
+
+		n += len(batch)
+	}
+
+	return
+}
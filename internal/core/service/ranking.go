@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"iter"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/rs/xid"
+)
+
+type Ranking struct {
+	store port.Ranking
+}
+
+func NewRanking(store port.Ranking) Ranking {
+	return Ranking{
+		store: store,
+	}
+}
+
+func (s Ranking) Create(ctx context.Context, ranking *domain.Ranking) (err error) {
+	defaultRankingDirections(ranking)
+
+	if err = ranking.Validate(); err != nil {
+		return
+	}
+
+	ranking.ID = xid.New()
+
+	return s.store.CreateRanking(ctx, ranking)
+}
+
+func (s Ranking) Read(ctx context.Context, ranking *domain.Ranking) (err error) {
+	return s.store.ReadRanking(ctx, ranking)
+}
+
+func (s Ranking) Update(ctx context.Context, ranking *domain.Ranking) (err error) {
+	defaultRankingDirections(ranking)
+
+	if err = ranking.Validate(); err != nil {
+		return
+	}
+
+	return s.store.UpdateRanking(ctx, ranking)
+}
+
+func (s Ranking) Delete(ctx context.Context, rankingId xid.ID) (err error) {
+	return s.store.DeleteRanking(ctx, rankingId)
+}
+
+func (s Ranking) Count(ctx context.Context, filters domain.RankingFilter) (int, error) {
+	return s.store.CountRankings(ctx, filters)
+}
+
+func (s Ranking) Iterate(ctx context.Context, filters domain.RankingFilter) iter.Seq2[*domain.Ranking, error] {
+	return s.store.IterateRankings(ctx, filters)
+}
+
+func (s Ranking) CountRanks(ctx context.Context, ranking domain.Ranking, filters domain.CompositeRankFilter) (int, error) {
+	return s.store.CountCompositeRanks(ctx, ranking, filters)
+}
+
+func (s Ranking) IterateRanks(ctx context.Context, ranking domain.Ranking, filters domain.CompositeRankFilter) iter.Seq2[*domain.CompositeRank, error] {
+	return s.store.IterateCompositeRanks(ctx, ranking, filters)
+}
+
+// Factors rank higher values as better unless told otherwise.
+func defaultRankingDirections(ranking *domain.Ranking) {
+	for i := range ranking.Factors {
+		if ranking.Factors[i].Direction == "" {
+			ranking.Factors[i].Direction = domain.RankingDirectionDesc
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"iter"
+	"log"
+	"time"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/rs/xid"
+)
+
+type SavedScreen struct {
+	store         port.SavedScreen
+	screenerStore port.Screener
+	scheduler     gocron.Scheduler
+}
+
+func NewSavedScreen(store port.SavedScreen, screenerStore port.Screener, scheduler gocron.Scheduler) SavedScreen {
+	return SavedScreen{
+		store:         store,
+		screenerStore: screenerStore,
+		scheduler:     scheduler,
+	}
+}
+
+// Snapshots tracked screens every night. The scheduler is started by the currency jobs.
+func (s SavedScreen) StartJobs(ctx context.Context) (err error) {
+	_, err = s.scheduler.NewJob(gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 0, 0))), gocron.NewTask(s.SnapshotScreens), gocron.WithContext(ctx))
+	return
+}
+
+func (s SavedScreen) Create(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	if err = validateScreenerFilter(screen.Filter); err != nil {
+		return
+	}
+
+	screen.ID = xid.New()
+
+	return s.store.CreateSavedScreen(ctx, screen)
+}
+
+func (s SavedScreen) Read(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	return s.store.ReadSavedScreen(ctx, screen)
+}
+
+func (s SavedScreen) Update(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	if err = validateScreenerFilter(screen.Filter); err != nil {
+		return
+	}
+
+	return s.store.UpdateSavedScreen(ctx, screen)
+}
+
+func (s SavedScreen) Delete(ctx context.Context, screenId xid.ID) (err error) {
+	return s.store.DeleteSavedScreen(ctx, screenId)
+}
+
+func (s SavedScreen) Count(ctx context.Context, filters domain.SavedScreenFilter) (int, error) {
+	return s.store.CountSavedScreens(ctx, filters)
+}
+
+func (s SavedScreen) Iterate(ctx context.Context, filters domain.SavedScreenFilter) iter.Seq2[*domain.SavedScreen, error] {
+	return s.store.IterateSavedScreens(ctx, filters)
+}
+
+// Reads a saved screen's filter, with the paging and fiscal year of the run applied.
+func (s SavedScreen) Filter(ctx context.Context, screenId xid.ID, run domain.SavedScreenRun) (filter domain.ScreenerFilter, err error) {
+	screen := domain.SavedScreen{ID: screenId}
+
+	if err = s.store.ReadSavedScreen(ctx, &screen); err != nil {
+		return
+	}
+
+	filter = screen.Filter
+	filter.Limit = run.Limit
+	filter.Offset = run.Offset
+	filter.Cursor = run.Cursor
+	filter.SkipTotal = run.SkipTotal
+
+	if run.FiscalYear != 0 {
+		filter.FiscalYear = run.FiscalYear
+	}
+
+	if run.Currency != "" {
+		filter.Currency = run.Currency
+	}
+
+	if run.FXPolicy != "" {
+		filter.FXPolicy = run.FXPolicy
+	}
+
+	if filter.Order == "" {
+		filter.Order = "asc"
+	}
+
+	if filter.OrderBy == "" {
+		filter.OrderBy = domain.ScreenerColumnName
+	}
+
+	if filter.SectorScope == "" {
+		filter.SectorScope = domain.SectorScopeAll
+	}
+
+	if filter.FXPolicy == "" {
+		filter.FXPolicy = domain.FXPolicyStandard
+	}
+
+	if filter.Valuation == "" {
+		filter.Valuation = domain.ValuationHistorical
+	}
+
+	if filter.Period == "" {
+		filter.Period = domain.PeriodKindAnnual
+	}
+
+	return
+}
+
+// Snapshots the current members of all tracked screens.
+func (s SavedScreen) SnapshotScreens(ctx context.Context) (err error) {
+	screens := make([]*domain.SavedScreen, 0)
+	filter := domain.SavedScreenFilter{
+		Order:   "asc",
+		OrderBy: "created",
+		Limit:   500,
+		Tracked: true,
+	}
+
+	for {
+		var n int
+
+		for screen, err := range s.store.IterateSavedScreens(ctx, filter) {
+			if err != nil {
+				return err
+			}
+
+			screens = append(screens, screen)
+			n++
+		}
+
+		if n < filter.Limit {
+			break
+		}
+
+		filter.Offset += n
+	}
+
+	for _, screen := range screens {
+		if err := s.SnapshotScreen(ctx, screen); err != nil {
+			log.Println("snapshot of screen", screen.ID, "failed:", err)
+		}
+	}
+
+	return
+}
+
+// Snapshots the top TrackLimit results of a screen.
+func (s SavedScreen) SnapshotScreen(ctx context.Context, screen *domain.SavedScreen) (err error) {
+	filter, err := s.Filter(ctx, screen.ID, domain.SavedScreenRun{Limit: screen.TrackLimit})
+
+	if err != nil {
+		return
+	}
+
+	if filter.FiscalYear == 0 {
+		now := time.Now()
+		y, _, _ := now.Date()
+		filter.FiscalYear = y - 1
+	}
+
+	snapshot := domain.ScreenSnapshot{
+		ID:         xid.New(),
+		ScreenID:   screen.ID,
+		FiscalYear: filter.FiscalYear,
+		Members:    make([]domain.ScreenSnapshotMember, 0, filter.Limit),
+	}
+
+	for row, err := range s.screenerStore.IterateScreener(ctx, filter) {
+		if err != nil {
+			return err
+		}
+
+		snapshot.Members = append(snapshot.Members, domain.ScreenSnapshotMember{
+			CompanyID: row.CompanyId,
+			Rank:      filter.Offset + len(snapshot.Members) + 1,
+		})
+	}
+
+	return s.store.CreateScreenSnapshot(ctx, &snapshot)
+}
+
+func (s SavedScreen) CountSnapshots(ctx context.Context, filters domain.ScreenSnapshotFilter) (int, error) {
+	return s.store.CountScreenSnapshots(ctx, filters)
+}
+
+func (s SavedScreen) IterateSnapshots(ctx context.Context, filters domain.ScreenSnapshotFilter) iter.Seq2[*domain.ScreenSnapshot, error] {
+	return s.store.IterateScreenSnapshots(ctx, filters)
+}
+
+// Compares two snapshots of a screen. Without snapshots, the two latest are compared.
+func (s SavedScreen) IterateChanges(ctx context.Context, screenId xid.ID, filters domain.ScreenDiffFilter) iter.Seq2[*domain.ScreenMemberChange, error] {
+	return func(yield func(*domain.ScreenMemberChange, error) bool) {
+		if filters.From.IsNil() || filters.To.IsNil() {
+			var latest []xid.ID
+
+			for snapshot, err := range s.store.IterateScreenSnapshots(ctx, domain.ScreenSnapshotFilter{Limit: 2, ScreenID: screenId}) {
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+
+				latest = append(latest, snapshot.ID)
+			}
+
+			if len(latest) < 2 {
+				yield(nil, domain.ErrTooFewSnapshots.Detailed("compare two snapshots by setting from and to", "from"))
+				return
+			}
+
+			filters.From, filters.To = latest[1], latest[0]
+		}
+
+		for change, err := range s.store.IterateScreenChanges(ctx, screenId, filters.From, filters.To) {
+			if !yield(change, err) {
+				return
+			}
+		}
+	}
+}
+
+func (s SavedScreen) IterateMembership(ctx context.Context, screenId xid.ID, companyId xid.ID) iter.Seq2[*domain.ScreenMembership, error] {
+	return s.store.IterateScreenMembership(ctx, screenId, companyId)
+}
+
+// Ensures that all metrics in a filter exist, as they aren't validated when decoded from JSON.
+func validateScreenerFilter(filter domain.ScreenerFilter) (err error) {
+	ids := append([]domain.MetricID{}, filter.Columns...)
+
+	if filter.OrderBy != "" {
+		ids = append(ids, filter.OrderBy)
+	}
+
+	for _, id := range ids {
+		if _, ok := domain.LookupMetric(id); !ok {
+			return domain.ErrUnknownMetric.Detailed("unknown metric \""+string(id)+"\"", "filter")
+		}
+	}
+
+	_, err = domain.ParseFilterExpression(filter.Expression)
+
+	return
+}
@@ -18,6 +18,16 @@ func NewScreener(store port.Screener) Screener {
 	}
 }
 
+func (s Screener) IterateMetrics(ctx context.Context) iter.Seq2[*domain.Metric, error] {
+	return func(yield func(*domain.Metric, error) bool) {
+		for i := range domain.Metrics {
+			if !yield(&domain.Metrics[i], nil) {
+				return
+			}
+		}
+	}
+}
+
 func (s Screener) CountMagicRanks(ctx context.Context, filters domain.MagicRankFilter) (int, error) {
 	return s.store.CountMagicRanks(ctx, filters)
 }
@@ -26,12 +36,20 @@ func (s Screener) IterateMagicRanks(ctx context.Context, filters domain.MagicRan
 	return s.store.IterateMagicRanks(ctx, filters)
 }
 
+func (s Screener) CountAggregates(ctx context.Context, filters domain.AggregateFilter) (int, error) {
+	return s.store.CountAggregates(ctx, filters)
+}
+
+func (s Screener) IterateAggregates(ctx context.Context, filters domain.AggregateFilter) iter.Seq2[*domain.Aggregate, error] {
+	return s.store.IterateAggregates(ctx, filters)
+}
+
 func (s Screener) CountScreener(ctx context.Context, filters domain.ScreenerFilter) (int, error) {
 	return s.store.CountScreener(ctx, filters)
 }
 
 func (s Screener) IterateScreener(ctx context.Context, filters domain.ScreenerFilter) iter.Seq2[*domain.Screener, error] {
-	if filters.OrderBy != "name" {
+	if filters.OrderBy != domain.ScreenerColumnName {
 		var hasColumn bool
 		for _, c := range filters.Columns {
 			if c == filters.OrderBy {
@@ -40,9 +58,42 @@ func (s Screener) IterateScreener(ctx context.Context, filters domain.ScreenerFi
 		}
 
 		if !hasColumn {
-			filters.OrderBy = "name"
+			filters.OrderBy = domain.ScreenerColumnName
 		}
 	}
 
 	return s.store.IterateScreener(ctx, filters)
 }
+
+// Iterates a page of the screener, counting the total first unless the filter opts out. Both are
+// read from the same snapshot, so the total always agrees with the rows. The total is -1 when it
+// isn't counted.
+func (s Screener) IterateScreenerPage(ctx context.Context, filters domain.ScreenerFilter, setTotal func(int)) iter.Seq2[*domain.Screener, error] {
+	return func(yield func(*domain.Screener, error) bool) {
+		ctx, err := s.store.AcquireContext(ctx, true)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer s.store.ReleaseContext(ctx)
+
+		count := -1
+
+		if !filters.SkipTotal {
+			if count, err = s.store.CountScreener(ctx, filters); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+
+		setTotal(count)
+
+		for row, err := range s.IterateScreener(ctx, filters) {
+			if !yield(row, err) || err != nil {
+				return
+			}
+		}
+	}
+}
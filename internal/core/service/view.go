@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"iter"
+
+	"github.com/dagulv/screener/internal/core/domain"
+	"github.com/dagulv/screener/internal/core/port"
+)
+
+type View struct {
+	store port.View
+}
+
+func NewView(store port.View) View {
+	return View{
+		store: store,
+	}
+}
+
+// Refreshes the materialized views, which must be done after financials, shares or currency rates
+// are ingested.
+func (s View) Refresh(ctx context.Context) error {
+	return s.store.RefreshViews(ctx)
+}
+
+func (s View) IterateRefreshes(ctx context.Context) iter.Seq2[*domain.ViewRefresh, error] {
+	return s.store.IterateViewRefreshes(ctx)
+}